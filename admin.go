@@ -0,0 +1,546 @@
+/* Copyright (c) 2021 vesoft inc. All rights reserved.
+ *
+ * This source code is licensed under Apache 2.0 License,
+ * attached with Common Clause Condition 1.0, found in the LICENSES directory.
+ */
+
+package nebula_go
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// QueryInfo is a running query returned by SHOW QUERIES
+type QueryInfo struct {
+	SessionID       int64
+	ExecutionPlanID int64
+	User            string
+	Host            string
+	StartTime       time.Time
+	Duration        time.Duration
+	Status          string
+	Query           string
+}
+
+// ShowQueries returns the queries running in the sessions of the user
+func (session *Session) ShowQueries() ([]QueryInfo, error) {
+	resSet, err := session.ExecuteAndCheck("SHOW QUERIES")
+	if err != nil {
+		return nil, fmt.Errorf("failed to show queries, %w", err)
+	}
+	return parseQueryInfos(resSet)
+}
+
+// KillQuery kills the query of the given session and execution plan
+func (session *Session) KillQuery(sessionID, planID int64) error {
+	if _, err := session.ExecuteAndCheck(fmt.Sprintf("KILL QUERY (session=%d, plan=%d)", sessionID, planID)); err != nil {
+		return fmt.Errorf("failed to kill query, %w", err)
+	}
+	return nil
+}
+
+func parseQueryInfos(resSet *ResultSet) ([]QueryInfo, error) {
+	infos := make([]QueryInfo, 0, resSet.GetRowSize())
+	for i := 0; i < resSet.GetRowSize(); i++ {
+		record, err := resSet.GetRowValuesByIndex(i)
+		if err != nil {
+			return nil, err
+		}
+		var info QueryInfo
+		if info.SessionID, err = recordInt(record, "SessionID"); err != nil {
+			return nil, err
+		}
+		if info.ExecutionPlanID, err = recordInt(record, "ExecutionPlanID"); err != nil {
+			return nil, err
+		}
+		// The other columns are informational, they are left empty if missing
+		info.User, _ = recordString(record, "User")
+		info.Host, _ = recordString(record, "Host")
+		info.Status, _ = recordString(record, "Status")
+		info.Query, _ = recordString(record, "Query")
+		if duration, err := recordInt(record, "DurationInUSec"); err == nil {
+			info.Duration = time.Duration(duration) * time.Microsecond
+		}
+		if val, err := record.GetValueByColName("StartTime"); err == nil {
+			info.StartTime, _ = val.AsGoTime()
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+func recordInt(record *Record, colName string) (int64, error) {
+	val, err := record.GetValueByColName(colName)
+	if err != nil {
+		return 0, err
+	}
+	return val.AsInt()
+}
+
+func recordString(record *Record, colName string) (string, error) {
+	val, err := record.GetValueByColName(colName)
+	if err != nil {
+		return "", err
+	}
+	return val.AsString()
+}
+
+// ServerCapabilities are the optional features of the graph service, see Session.Capabilities
+type ServerCapabilities struct {
+	// Whether SHOW QUERIES and KILL QUERY are supported, see ShowQueries, since Nebula Graph 2.5
+	ShowQueries bool
+	// Whether SHOW SESSIONS is supported, since Nebula Graph 2.5
+	ShowSessions bool
+}
+
+// Capabilities returns the optional features of the graph service the session is connected to.
+// The v2 protocol does not report the version of the server, so the features are probed by
+// executing harmless statements, once per connection: the result is cached by the connection
+// and probed again when the session is reconnected or failed over to another connection.
+func (session *Session) Capabilities() (ServerCapabilities, error) {
+	session.mu.Lock()
+	conn := session.connection
+	var cached *ServerCapabilities
+	if conn != nil {
+		cached = conn.capabilities
+	}
+	session.mu.Unlock()
+	if conn == nil {
+		return ServerCapabilities{}, fmt.Errorf("failed to probe capabilities: Session has been released")
+	}
+	if cached != nil {
+		return *cached, nil
+	}
+	caps := &ServerCapabilities{}
+	var err error
+	if caps.ShowQueries, err = session.isSupported("SHOW QUERIES"); err != nil {
+		return ServerCapabilities{}, err
+	}
+	if caps.ShowSessions, err = session.isSupported("SHOW SESSIONS"); err != nil {
+		return ServerCapabilities{}, err
+	}
+	session.mu.Lock()
+	if session.connection == conn {
+		conn.capabilities = caps
+	}
+	session.mu.Unlock()
+	return *caps, nil
+}
+
+// Check if the statement is supported by the server, i.e. it does not fail with a syntax error
+func (session *Session) isSupported(stmt string) (bool, error) {
+	resSet, err := session.Execute(stmt)
+	if err != nil {
+		return false, fmt.Errorf("failed to probe capabilities, %w", err)
+	}
+	return resSet.GetErrorCode() != ErrorCode_E_SYNTAX_ERROR, nil
+}
+
+// SpaceSchema is the schema of a graph space returned by DescribeSpace
+type SpaceSchema struct {
+	Name        string
+	Tags        []SchemaInfo
+	Edges       []SchemaInfo
+	TagIndexes  []IndexInfo
+	EdgeIndexes []IndexInfo
+}
+
+// SchemaInfo is a tag or an edge type with its properties
+type SchemaInfo struct {
+	Name       string
+	Properties []PropertyInfo
+}
+
+// PropertyInfo is a property of a tag or an edge type
+type PropertyInfo struct {
+	Name     string
+	Type     string
+	Nullable bool
+	// The default value of the property, nil if the property has no default value
+	Default *ValueWrapper
+}
+
+// IndexInfo is an index on a tag or an edge type
+type IndexInfo struct {
+	Name string
+	// The tag or edge type the index is on, empty if not reported by the server
+	Schema string
+	Fields []string
+}
+
+// DescribeSpace returns the tags, edge types and indexes of the given space.
+// The session is switched to the space to describe it, and switched back to its current space afterwards
+// if it has one. The schema is returned with an error if the session fails to switch back.
+func (session *Session) DescribeSpace(space string) (schema *SpaceSchema, err error) {
+	resSet, err := session.ExecuteAndCheck("YIELD 1")
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe space %s, %w", space, err)
+	}
+	if prevSpace := resSet.GetSpaceName(); prevSpace != "" && prevSpace != space {
+		defer func() {
+			if useErr := session.Use(prevSpace); useErr != nil && err == nil {
+				err = fmt.Errorf("failed to switch back after describing space %s, %w", space, useErr)
+			}
+		}()
+	}
+	schema, err = session.describeSpace(space)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe space %s, %w", space, err)
+	}
+	return schema, nil
+}
+
+// VIDType is the type of the vertex IDs of a space, INT64 or FIXED_STRING(Length)
+type VIDType struct {
+	IsInt  bool
+	Length int // the max length of the string IDs, 0 for INT64
+}
+
+func (t VIDType) String() string {
+	if t.IsInt {
+		return "INT64"
+	}
+	return fmt.Sprintf("FIXED_STRING(%d)", t.Length)
+}
+
+// VIDType returns the type of the vertex IDs of the given space, e.g. to tell whether the IDs
+// are quoted in a statement. The type is queried with DESCRIBE SPACE once and cached by the session,
+// the cache is not invalidated if the space is dropped and created again with another type.
+func (session *Session) VIDType(space string) (VIDType, error) {
+	session.mu.Lock()
+	vidType, ok := session.vidTypes[space]
+	session.mu.Unlock()
+	if ok {
+		return vidType, nil
+	}
+	if space == "" || strings.ContainsAny(space, "`\n") {
+		return VIDType{}, fmt.Errorf("failed to get VID type: invalid space name %q", space)
+	}
+	resSet, err := session.ExecuteAndCheck(fmt.Sprintf("DESCRIBE SPACE `%s`", space))
+	if err != nil {
+		return VIDType{}, fmt.Errorf("failed to get VID type of space %s, %w", space, err)
+	}
+	if vidType, err = parseVIDType(resSet); err != nil {
+		return VIDType{}, fmt.Errorf("failed to get VID type of space %s, %w", space, err)
+	}
+	session.mu.Lock()
+	if session.vidTypes == nil {
+		session.vidTypes = make(map[string]VIDType)
+	}
+	session.vidTypes[space] = vidType
+	session.mu.Unlock()
+	return vidType, nil
+}
+
+// Parse the Vid Type column of the result of DESCRIBE SPACE, e.g. INT64 or FIXED_STRING(32)
+func parseVIDType(resSet *ResultSet) (VIDType, error) {
+	if resSet.GetRowSize() != 1 {
+		return VIDType{}, fmt.Errorf("unexpected %d rows of the space", resSet.GetRowSize())
+	}
+	record, err := resSet.GetRowValuesByIndex(0)
+	if err != nil {
+		return VIDType{}, err
+	}
+	raw, err := recordString(record, "Vid Type")
+	if err != nil {
+		return VIDType{}, err
+	}
+	if raw == "INT64" {
+		return VIDType{IsInt: true}, nil
+	}
+	var length int
+	if _, err := fmt.Sscanf(raw, "FIXED_STRING(%d)", &length); err != nil || length <= 0 {
+		return VIDType{}, fmt.Errorf("unknown VID type %s", raw)
+	}
+	return VIDType{Length: length}, nil
+}
+
+func (session *Session) describeSpace(space string) (*SpaceSchema, error) {
+	if err := session.Use(space); err != nil {
+		return nil, err
+	}
+	schema := &SpaceSchema{Name: space}
+	var err error
+	if schema.Tags, err = session.describeSchemas("TAG"); err != nil {
+		return nil, err
+	}
+	if schema.Edges, err = session.describeSchemas("EDGE"); err != nil {
+		return nil, err
+	}
+	if schema.TagIndexes, err = session.describeIndexes("TAG"); err != nil {
+		return nil, err
+	}
+	if schema.EdgeIndexes, err = session.describeIndexes("EDGE"); err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// Describe the tags or the edge types of the current space, kind is TAG or EDGE
+func (session *Session) describeSchemas(kind string) ([]SchemaInfo, error) {
+	resSet, err := session.ExecuteAndCheck(fmt.Sprintf("SHOW %sS", kind))
+	if err != nil {
+		return nil, err
+	}
+	names, err := parseColumnStrings(resSet, "Name")
+	if err != nil {
+		return nil, err
+	}
+	schemas := make([]SchemaInfo, 0, len(names))
+	for _, name := range names {
+		resSet, err = session.ExecuteAndCheck(fmt.Sprintf("DESCRIBE %s `%s`", kind, name))
+		if err != nil {
+			return nil, err
+		}
+		props, err := parsePropertyInfos(resSet)
+		if err != nil {
+			return nil, err
+		}
+		schemas = append(schemas, SchemaInfo{Name: name, Properties: props})
+	}
+	return schemas, nil
+}
+
+// Describe the tag or the edge indexes of the current space, kind is TAG or EDGE
+func (session *Session) describeIndexes(kind string) ([]IndexInfo, error) {
+	resSet, err := session.ExecuteAndCheck(fmt.Sprintf("SHOW %s INDEXES", kind))
+	if err != nil {
+		return nil, err
+	}
+	indexes, err := parseIndexInfos(resSet)
+	if err != nil {
+		return nil, err
+	}
+	for i := range indexes {
+		resSet, err = session.ExecuteAndCheck(fmt.Sprintf("DESCRIBE %s INDEX `%s`", kind, indexes[i].Name))
+		if err != nil {
+			return nil, err
+		}
+		if indexes[i].Fields, err = parseColumnStrings(resSet, "Field"); err != nil {
+			return nil, err
+		}
+	}
+	return indexes, nil
+}
+
+// Return the string values of the given column
+func parseColumnStrings(resSet *ResultSet, colName string) ([]string, error) {
+	vals, err := resSet.GetValuesByColName(colName)
+	if err != nil {
+		return nil, err
+	}
+	strs := make([]string, 0, len(vals))
+	for _, val := range vals {
+		s, err := val.AsString()
+		if err != nil {
+			return nil, err
+		}
+		strs = append(strs, s)
+	}
+	return strs, nil
+}
+
+// Parse the result of DESCRIBE TAG or DESCRIBE EDGE
+func parsePropertyInfos(resSet *ResultSet) ([]PropertyInfo, error) {
+	props := make([]PropertyInfo, 0, resSet.GetRowSize())
+	for i := 0; i < resSet.GetRowSize(); i++ {
+		record, err := resSet.GetRowValuesByIndex(i)
+		if err != nil {
+			return nil, err
+		}
+		var prop PropertyInfo
+		if prop.Name, err = recordString(record, "Field"); err != nil {
+			return nil, err
+		}
+		if prop.Type, err = recordString(record, "Type"); err != nil {
+			return nil, err
+		}
+		if null, err := recordString(record, "Null"); err == nil {
+			prop.Nullable = null == "YES"
+		}
+		if val, err := record.GetValueByColName("Default"); err == nil && !val.IsEmpty() {
+			prop.Default = val
+		}
+		props = append(props, prop)
+	}
+	return props, nil
+}
+
+// Parse the result of SHOW TAG INDEXES or SHOW EDGE INDEXES
+func parseIndexInfos(resSet *ResultSet) ([]IndexInfo, error) {
+	nameCol := "Index Name"
+	if !resSet.hasColName(nameCol) {
+		// Servers before 2.5 only return the names
+		nameCol = "Names"
+	}
+	schemaCol := ""
+	for _, col := range []string{"By Tag", "By Edge"} {
+		if resSet.hasColName(col) {
+			schemaCol = col
+		}
+	}
+	indexes := make([]IndexInfo, 0, resSet.GetRowSize())
+	for i := 0; i < resSet.GetRowSize(); i++ {
+		record, err := resSet.GetRowValuesByIndex(i)
+		if err != nil {
+			return nil, err
+		}
+		var index IndexInfo
+		if index.Name, err = recordString(record, nameCol); err != nil {
+			return nil, err
+		}
+		if schemaCol != "" {
+			index.Schema, _ = recordString(record, schemaCol)
+		}
+		indexes = append(indexes, index)
+	}
+	return indexes, nil
+}
+
+// The interval of WaitForIndex if none is given
+const defaultIndexPollInterval = time.Second
+
+// WaitForIndex polls the status of the tag or edge index of the current space every interval,
+// until its rebuild reports FINISHED. An error is returned if the rebuild reports FAILED, STOPPED
+// or INVALID, and an error wrapping ctx.Err() is returned if ctx is done before.
+// The index is polled until ctx is done if its rebuild is not listed, e.g. if it is not submitted yet.
+// An interval <= 0 means the default of 1 second.
+func (session *Session) WaitForIndex(ctx context.Context, indexName string, interval time.Duration) error {
+	if interval <= 0 {
+		interval = defaultIndexPollInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		status, err := session.indexStatus(indexName)
+		if err != nil {
+			return fmt.Errorf("failed to wait for index %s, %w", indexName, err)
+		}
+		switch status {
+		case "FINISHED":
+			return nil
+		case "FAILED", "STOPPED", "INVALID":
+			return fmt.Errorf("failed to wait for index %s, the rebuild is %s", indexName, status)
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return fmt.Errorf("failed to wait for index %s, last status: %q, %w", indexName, status, ctx.Err())
+		}
+	}
+}
+
+// Return the rebuild status of the tag or edge index, empty if the index is not listed
+func (session *Session) indexStatus(indexName string) (string, error) {
+	for _, kind := range []string{"TAG", "EDGE"} {
+		resSet, err := session.ExecuteAndCheck(fmt.Sprintf("SHOW %s INDEX STATUS", kind))
+		if err != nil {
+			return "", err
+		}
+		status, err := parseIndexStatus(resSet, indexName)
+		if err != nil || status != "" {
+			return status, err
+		}
+	}
+	return "", nil
+}
+
+// Parse the status of the index from the result of SHOW TAG INDEX STATUS or SHOW EDGE INDEX STATUS
+func parseIndexStatus(resSet *ResultSet, indexName string) (string, error) {
+	for i := 0; i < resSet.GetRowSize(); i++ {
+		record, err := resSet.GetRowValuesByIndex(i)
+		if err != nil {
+			return "", err
+		}
+		name, err := recordString(record, "Name")
+		if err != nil {
+			return "", err
+		}
+		if name == indexName {
+			return recordString(record, "Index Status")
+		}
+	}
+	return "", nil
+}
+
+// HostInfo is a storage host returned by SHOW HOSTS
+type HostInfo struct {
+	Host   string
+	Port   int64
+	Status string
+	// Number of partitions the host is the leader of
+	LeaderCount int64
+	// Number of partitions the host is the leader of and holds, keyed by space,
+	// empty if not reported by the server
+	LeaderDistribution    map[string]int64
+	PartitionDistribution map[string]int64
+}
+
+// ShowHosts returns the storage hosts of the cluster
+func (session *Session) ShowHosts() ([]HostInfo, error) {
+	resSet, err := session.ExecuteAndCheck("SHOW HOSTS")
+	if err != nil {
+		return nil, fmt.Errorf("failed to show hosts, %w", err)
+	}
+	return parseHostInfos(resSet)
+}
+
+// ShowSpaces returns the names of the spaces
+func (session *Session) ShowSpaces() ([]string, error) {
+	resSet, err := session.ExecuteAndCheck("SHOW SPACES")
+	if err != nil {
+		return nil, fmt.Errorf("failed to show spaces, %w", err)
+	}
+	return parseColumnStrings(resSet, "Name")
+}
+
+func parseHostInfos(resSet *ResultSet) ([]HostInfo, error) {
+	hosts := make([]HostInfo, 0, resSet.GetRowSize())
+	for i := 0; i < resSet.GetRowSize(); i++ {
+		record, err := resSet.GetRowValuesByIndex(i)
+		if err != nil {
+			return nil, err
+		}
+		var host HostInfo
+		if host.Host, err = recordString(record, "Host"); err != nil {
+			return nil, err
+		}
+		if host.Port, err = recordInt(record, "Port"); err != nil {
+			// Servers before 2.5 add a row of the totals without a port
+			continue
+		}
+		// The other columns are informational, they are left empty if missing
+		host.Status, _ = recordString(record, "Status")
+		host.LeaderCount, _ = recordInt(record, "Leader count")
+		if dist, err := recordString(record, "Leader distribution"); err == nil {
+			host.LeaderDistribution = parseDistribution(dist)
+		}
+		if dist, err := recordString(record, "Partition distribution"); err == nil {
+			host.PartitionDistribution = parseDistribution(dist)
+		}
+		hosts = append(hosts, host)
+	}
+	return hosts, nil
+}
+
+// Parse a distribution of partitions such as "space1:10, space2:5",
+// the other text such as "No valid partition" is ignored
+func parseDistribution(dist string) map[string]int64 {
+	counts := make(map[string]int64)
+	for _, item := range strings.Split(dist, ",") {
+		i := strings.LastIndex(item, ":")
+		if i < 0 {
+			continue
+		}
+		count, err := strconv.ParseInt(strings.TrimSpace(item[i+1:]), 10, 64)
+		if err != nil {
+			continue
+		}
+		counts[strings.TrimSpace(item[:i])] = count
+	}
+	return counts
+}
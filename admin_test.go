@@ -0,0 +1,254 @@
+/* Copyright (c) 2021 vesoft inc. All rights reserved.
+ *
+ * This source code is licensed under Apache 2.0 License,
+ * attached with Common Clause Condition 1.0, found in the LICENSES directory.
+ */
+
+package nebula_go
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vesoft-inc/nebula-go/v2/fake"
+	"github.com/vesoft-inc/nebula-go/v2/nebula"
+	"github.com/vesoft-inc/nebula-go/v2/nebula/graph"
+)
+
+func TestParseQueryInfos(t *testing.T) {
+	dataset := &nebula.DataSet{
+		ColumnNames: [][]byte{
+			[]byte("SessionID"), []byte("ExecutionPlanID"), []byte("User"), []byte("Host"),
+			[]byte("StartTime"), []byte("DurationInUSec"), []byte("Status"), []byte("Query"),
+		},
+		Rows: []*nebula.Row{{Values: []*nebula.Value{
+			setIVal(1), setIVal(2), {SVal: []byte("root")}, {SVal: []byte("127.0.0.1:9669")},
+			{DtVal: &nebula.DateTime{Year: 2021, Month: 1, Day: 2, Hour: 3, Minute: 4, Sec: 5}},
+			setIVal(1500), {SVal: []byte("RUNNING")}, {SVal: []byte("SHOW QUERIES")},
+		}}},
+	}
+	resp := &graph.ExecutionResponse{ErrorCode: nebula.ErrorCode_SUCCEEDED, Data: dataset}
+	resultSet, err := genResultSet(resp, testTimezone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	infos, err := parseQueryInfos(resultSet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, len(infos))
+	assert.Equal(t, int64(1), infos[0].SessionID)
+	assert.Equal(t, int64(2), infos[0].ExecutionPlanID)
+	assert.Equal(t, "root", infos[0].User)
+	assert.Equal(t, "127.0.0.1:9669", infos[0].Host)
+	assert.True(t, time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC).Equal(infos[0].StartTime))
+	assert.Equal(t, 1500*time.Microsecond, infos[0].Duration)
+	assert.Equal(t, "RUNNING", infos[0].Status)
+	assert.Equal(t, "SHOW QUERIES", infos[0].Query)
+}
+
+func TestParseSchema(t *testing.T) {
+	defaultVal := int64(18)
+	dataset := &nebula.DataSet{
+		ColumnNames: [][]byte{[]byte("Field"), []byte("Type"), []byte("Null"), []byte("Default")},
+		Rows: []*nebula.Row{
+			{Values: []*nebula.Value{{SVal: []byte("name")}, {SVal: []byte("string")}, {SVal: []byte("NO")}, {}}},
+			{Values: []*nebula.Value{{SVal: []byte("age")}, {SVal: []byte("int64")}, {SVal: []byte("YES")}, {IVal: &defaultVal}}},
+		},
+	}
+	resp := &graph.ExecutionResponse{ErrorCode: nebula.ErrorCode_SUCCEEDED, Data: dataset}
+	resultSet, err := genResultSet(resp, testTimezone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	props, err := parsePropertyInfos(resultSet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, len(props))
+	assert.Equal(t, "name", props[0].Name)
+	assert.Equal(t, "string", props[0].Type)
+	assert.False(t, props[0].Nullable)
+	assert.Nil(t, props[0].Default)
+	assert.Equal(t, "age", props[1].Name)
+	assert.True(t, props[1].Nullable)
+	age, _ := props[1].Default.AsInt()
+	assert.Equal(t, int64(18), age)
+
+	dataset = &nebula.DataSet{
+		ColumnNames: [][]byte{[]byte("Index Name"), []byte("By Tag"), []byte("Columns")},
+		Rows: []*nebula.Row{
+			{Values: []*nebula.Value{{SVal: []byte("person_index")}, {SVal: []byte("person")}, {}}},
+		},
+	}
+	resp = &graph.ExecutionResponse{ErrorCode: nebula.ErrorCode_SUCCEEDED, Data: dataset}
+	resultSet, err = genResultSet(resp, testTimezone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	indexes, err := parseIndexInfos(resultSet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []IndexInfo{{Name: "person_index", Schema: "person"}}, indexes)
+}
+
+func TestParseIndexStatus(t *testing.T) {
+	dataset := &nebula.DataSet{
+		ColumnNames: [][]byte{[]byte("Name"), []byte("Index Status")},
+		Rows: []*nebula.Row{
+			{Values: []*nebula.Value{{SVal: []byte("person_index")}, {SVal: []byte("RUNNING")}}},
+			{Values: []*nebula.Value{{SVal: []byte("name_index")}, {SVal: []byte("FINISHED")}}},
+		},
+	}
+	resp := &graph.ExecutionResponse{ErrorCode: nebula.ErrorCode_SUCCEEDED, Data: dataset}
+	resultSet, err := genResultSet(resp, testTimezone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	status, err := parseIndexStatus(resultSet, "name_index")
+	assert.Nil(t, err)
+	assert.Equal(t, "FINISHED", status)
+	status, err = parseIndexStatus(resultSet, "person_index")
+	assert.Nil(t, err)
+	assert.Equal(t, "RUNNING", status)
+	status, err = parseIndexStatus(resultSet, "missing_index")
+	assert.Nil(t, err)
+	assert.Equal(t, "", status)
+}
+
+func TestParseHostInfos(t *testing.T) {
+	dataset := &nebula.DataSet{
+		ColumnNames: [][]byte{
+			[]byte("Host"), []byte("Port"), []byte("Status"), []byte("Leader count"),
+			[]byte("Leader distribution"), []byte("Partition distribution"),
+		},
+		Rows: []*nebula.Row{
+			{Values: []*nebula.Value{
+				{SVal: []byte("storaged0")}, setIVal(9779), {SVal: []byte("ONLINE")}, setIVal(3),
+				{SVal: []byte("nba:2, test:1")}, {SVal: []byte("nba:5, test:3")},
+			}},
+			{Values: []*nebula.Value{
+				{SVal: []byte("storaged1")}, setIVal(9779), {SVal: []byte("OFFLINE")}, setIVal(0),
+				{SVal: []byte("No valid partition")}, {SVal: []byte("No valid partition")},
+			}},
+			{Values: []*nebula.Value{
+				{SVal: []byte("Total")}, {}, {}, setIVal(3),
+				{SVal: []byte("nba:2, test:1")}, {SVal: []byte("nba:5, test:3")},
+			}},
+		},
+	}
+	resp := &graph.ExecutionResponse{ErrorCode: nebula.ErrorCode_SUCCEEDED, Data: dataset}
+	resultSet, err := genResultSet(resp, testTimezone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hosts, err := parseHostInfos(resultSet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []HostInfo{
+		{
+			Host: "storaged0", Port: 9779, Status: "ONLINE", LeaderCount: 3,
+			LeaderDistribution:    map[string]int64{"nba": 2, "test": 1},
+			PartitionDistribution: map[string]int64{"nba": 5, "test": 3},
+		},
+		{
+			Host: "storaged1", Port: 9779, Status: "OFFLINE",
+			LeaderDistribution:    map[string]int64{},
+			PartitionDistribution: map[string]int64{},
+		},
+	}, hosts)
+}
+
+func TestVIDType(t *testing.T) {
+	service := fake.NewGraphService()
+	columns := []string{"ID", "Name", "Partition Number", "Replica Factor", "Charset", "Collate", "Vid Type"}
+	describe := func(name, vidType string) []*nebula.Value {
+		return []*nebula.Value{setIVal(1), {SVal: []byte(name)}, setIVal(10), setIVal(1),
+			{SVal: []byte("utf8")}, {SVal: []byte("utf8_bin")}, {SVal: []byte(vidType)}}
+	}
+	service.SetResult("DESCRIBE SPACE `ints`", columns, describe("ints", "INT64"))
+	service.SetResult("DESCRIBE SPACE `strings`", columns, describe("strings", "FIXED_STRING(32)"))
+	service.SetResult("DESCRIBE SPACE `unknown`", columns, describe("unknown", "STRING"))
+	service.SetError("DESCRIBE SPACE `missing`", nebula.ErrorCode_E_SPACE_NOT_FOUND, "SpaceNotFound")
+
+	session, closeSession := newFakeSession(t, service)
+	defer closeSession()
+
+	vidType, err := session.VIDType("ints")
+	assert.Nil(t, err)
+	assert.Equal(t, VIDType{IsInt: true}, vidType)
+	assert.Equal(t, "INT64", vidType.String())
+	vidType, err = session.VIDType("strings")
+	assert.Nil(t, err)
+	assert.Equal(t, VIDType{Length: 32}, vidType)
+	assert.Equal(t, "FIXED_STRING(32)", vidType.String())
+	// Cached
+	_, err = session.VIDType("ints")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"DESCRIBE SPACE `ints`", "DESCRIBE SPACE `strings`"}, service.Executed())
+
+	_, err = session.VIDType("unknown")
+	assert.NotNil(t, err)
+	_, err = session.VIDType("missing")
+	assert.True(t, errors.Is(err, ErrSpaceNotFound))
+	_, err = session.VIDType("bad`name")
+	assert.NotNil(t, err)
+}
+
+func TestDescribeSpace(t *testing.T) {
+	service := fake.NewGraphService()
+	service.SetResult("SHOW TAGS", []string{"Name"}, []*nebula.Value{{SVal: []byte("person")}})
+	service.SetResult("DESCRIBE TAG `person`", []string{"Field", "Type", "Null", "Default"},
+		[]*nebula.Value{{SVal: []byte("name")}, {SVal: []byte("string")}, {SVal: []byte("NO")}, {}})
+	service.SetResult("SHOW EDGES", []string{"Name"})
+	service.SetResult("SHOW TAG INDEXES", []string{"Index Name", "By Tag", "Columns"})
+	service.SetResult("SHOW EDGE INDEXES", []string{"Index Name", "By Edge", "Columns"})
+
+	session, closeSession := newFakeSession(t, service)
+	defer closeSession()
+
+	assert.Nil(t, session.Use("base"))
+	schema, err := session.DescribeSpace("test")
+	assert.Nil(t, err)
+	assert.Equal(t, "test", schema.Name)
+	assert.Equal(t, []SchemaInfo{{Name: "person", Properties: []PropertyInfo{{Name: "name", Type: "string"}}}},
+		schema.Tags)
+	// The session is switched back to its space
+	assert.Equal(t, "base", session.CurrentSpace())
+	assert.Equal(t, "USE `base`", service.Executed()[len(service.Executed())-1])
+
+	// The schema is returned with the error of switching back
+	service.SetError("USE `base`", nebula.ErrorCode_E_SPACE_NOT_FOUND, "SpaceNotFound")
+	schema, err = session.DescribeSpace("test")
+	assert.NotNil(t, schema)
+	assert.True(t, errors.Is(err, ErrSpaceNotFound))
+	assert.Contains(t, err.Error(), "failed to switch back")
+	assert.Equal(t, "test", session.CurrentSpace())
+}
+
+func TestCapabilities(t *testing.T) {
+	service := fake.NewGraphService()
+	service.SetError("SHOW SESSIONS", nebula.ErrorCode_E_SYNTAX_ERROR, "syntax error near `SESSIONS'")
+	service.SetError("SHOW QUERIES", nebula.ErrorCode_E_BAD_PERMISSION, "No permission")
+
+	session, closeSession := newFakeSession(t, service)
+	defer closeSession()
+
+	caps, err := session.Capabilities()
+	assert.Nil(t, err)
+	// A statement failing with another error than a syntax error is supported
+	assert.Equal(t, ServerCapabilities{ShowQueries: true, ShowSessions: false}, caps)
+	// Cached by the connection
+	caps, err = session.Capabilities()
+	assert.Nil(t, err)
+	assert.True(t, caps.ShowQueries)
+	assert.Equal(t, []string{"SHOW QUERIES", "SHOW SESSIONS"}, service.Executed())
+
+	session.Release()
+	_, err = session.Capabilities()
+	assert.NotNil(t, err)
+}
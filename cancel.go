@@ -0,0 +1,136 @@
+/* Copyright (c) 2021 vesoft inc. All rights reserved.
+ *
+ * This source code is licensed under Apache 2.0 License,
+ * attached with Common Clause Condition 1.0, found in the LICENSES directory.
+ */
+
+package nebula_go
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// QueryHandle is a query executed in the background by Session.ExecuteAsync,
+// it can be cancelled from another goroutine, e.g. when the user of an interactive tool hits stop.
+type QueryHandle struct {
+	session   *Session
+	sessionID int64
+	stmt      string
+	cancel    context.CancelFunc
+	done      chan struct{}
+	resSet    *ResultSet
+	err       error
+
+	mu     sync.Mutex
+	planID int64 // the execution plan of the query on the server, 0 until it is found by Cancel
+}
+
+// ExecuteAsync executes the query as ExecuteWithContext in a new goroutine and returns its handle
+// right away. The result is returned by Wait. The session executes one query at a time, so the
+// other queries of the session wait for the query to complete.
+func (session *Session) ExecuteAsync(ctx context.Context, stmt string) *QueryHandle {
+	ctx, cancel := context.WithCancel(ctx)
+	handle := &QueryHandle{
+		session:   session,
+		sessionID: session.SessionID(),
+		stmt:      stmt,
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+	go func() {
+		defer cancel()
+		handle.resSet, handle.err = session.ExecuteWithContext(ctx, stmt)
+		close(handle.done)
+	}()
+	return handle
+}
+
+// Wait waits for the query to complete and returns its result as ExecuteWithContext.
+// The returned error wraps context.Canceled if the query was cancelled on the client side.
+func (handle *QueryHandle) Wait() (*ResultSet, error) {
+	<-handle.done
+	return handle.resSet, handle.err
+}
+
+// Done returns a channel closed when the query completed
+func (handle *QueryHandle) Done() <-chan struct{} {
+	return handle.done
+}
+
+// SessionID returns the ID of the session executing the query on the server
+func (handle *QueryHandle) SessionID() int64 {
+	return handle.sessionID
+}
+
+// PlanID returns the ID of the execution plan of the query on the server,
+// it is 0 until it is found by Cancel
+func (handle *QueryHandle) PlanID() int64 {
+	handle.mu.Lock()
+	defer handle.mu.Unlock()
+	return handle.planID
+}
+
+// Cancel stops the query. The session of the query is busy until it completes, so the plan of the
+// query is looked up with SHOW QUERIES and killed with KILL QUERY in killer, another session of
+// the same user, see Session.Capabilities. The query is then aborted on the client side, in which
+// case the connection of its session is reopened as by ExecuteWithContext.
+//
+// The server reports the plan of a query only once it is running: before the statement reaches the
+// server and is planned, or if killer is nil, the query is only aborted on the client side and keeps
+// running on the server until it completes. An error is returned in this case, or if the query
+// could not be killed, unless the query completed meanwhile. Cancelling a completed query does nothing.
+func (handle *QueryHandle) Cancel(killer *Session) error {
+	select {
+	case <-handle.done:
+		return nil
+	default:
+	}
+	var err error
+	if killer == nil {
+		err = fmt.Errorf("failed to cancel query: no session to kill the query of session %d on the server",
+			handle.sessionID)
+	} else if killer == handle.session {
+		err = fmt.Errorf("failed to cancel query: the query cannot be killed by its own session")
+	} else {
+		err = handle.kill(killer)
+	}
+	handle.cancel()
+	if err != nil {
+		select {
+		case <-handle.done:
+			// The query completed meanwhile
+			return nil
+		default:
+		}
+	}
+	return err
+}
+
+// Look up the plan of the query and kill it in killer
+func (handle *QueryHandle) kill(killer *Session) error {
+	queries, err := killer.ShowQueries()
+	if err != nil {
+		return fmt.Errorf("failed to cancel query, %w", err)
+	}
+	var planID int64
+	for _, query := range queries {
+		// A session executes one query at a time
+		if query.SessionID == handle.sessionID && query.Query == handle.stmt {
+			planID = query.ExecutionPlanID
+			break
+		}
+	}
+	if planID == 0 {
+		return fmt.Errorf("failed to cancel query: the query of session %d is not running on the server",
+			handle.sessionID)
+	}
+	handle.mu.Lock()
+	handle.planID = planID
+	handle.mu.Unlock()
+	if err := killer.KillQuery(handle.sessionID, planID); err != nil {
+		return fmt.Errorf("failed to cancel query, %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+/* Copyright (c) 2021 vesoft inc. All rights reserved.
+ *
+ * This source code is licensed under Apache 2.0 License,
+ * attached with Common Clause Condition 1.0, found in the LICENSES directory.
+ */
+
+package nebula_go
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vesoft-inc/nebula-go/v2/fake"
+	"github.com/vesoft-inc/nebula-go/v2/nebula"
+	"github.com/vesoft-inc/nebula-go/v2/nebula/graph"
+)
+
+// A graph service running the slow statement until it is killed
+type killableService struct {
+	*fake.GraphService
+	slow    string
+	running chan struct{}
+	killed  chan struct{}
+}
+
+func (s *killableService) Execute(sessionId int64, stmt []byte) (*graph.ExecutionResponse, error) {
+	switch string(stmt) {
+	case s.slow:
+		close(s.running)
+		<-s.killed
+		return &graph.ExecutionResponse{ErrorCode: nebula.ErrorCode_E_EXECUTION_ERROR, ErrorMsg: []byte("Killed")}, nil
+	case "KILL QUERY (session=1, plan=7)":
+		close(s.killed)
+	}
+	return s.GraphService.Execute(sessionId, stmt)
+}
+
+func TestQueryHandleCancel(t *testing.T) {
+	const slow = "GO 10 STEPS FROM \"p1\" OVER follow"
+	service := &killableService{
+		GraphService: fake.NewGraphService(),
+		slow:         slow,
+		running:      make(chan struct{}),
+		killed:       make(chan struct{}),
+	}
+	service.SetResult("SHOW QUERIES", []string{"SessionID", "ExecutionPlanID", "Query"},
+		[]*nebula.Value{setIVal(2), setIVal(3), {SVal: []byte("SHOW QUERIES")}},
+		[]*nebula.Value{setIVal(1), setIVal(7), {SVal: []byte(slow)}})
+	session, closeSession := newFakeSession(t, service)
+	defer closeSession()
+	killer, err := session.connPool.GetSession("root", "nebula")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer killer.Release()
+
+	handle := session.ExecuteAsync(context.Background(), slow)
+	<-service.running
+	assert.Equal(t, int64(1), handle.SessionID())
+	assert.Equal(t, int64(0), handle.PlanID())
+	assert.Nil(t, handle.Cancel(killer))
+	assert.Equal(t, int64(7), handle.PlanID())
+	resSet, err := handle.Wait()
+	assert.Nil(t, err)
+	assert.Equal(t, ErrorCode_E_EXECUTION_ERROR, resSet.GetErrorCode())
+	// Cancelling a completed query does nothing
+	assert.Nil(t, handle.Cancel(nil))
+
+	handle = session.ExecuteAsync(context.Background(), "YIELD 1")
+	<-handle.Done()
+	resSet, err = handle.Wait()
+	assert.Nil(t, err)
+	assert.True(t, resSet.IsSucceed())
+
+	// The query is not running on the server yet
+	pending := &QueryHandle{session: session, sessionID: 1, stmt: "YIELD 1", cancel: func() {}, done: make(chan struct{})}
+	assert.NotNil(t, pending.Cancel(killer))
+	assert.NotNil(t, pending.Cancel(session))
+	assert.NotNil(t, pending.Cancel(nil))
+}
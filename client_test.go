@@ -7,8 +7,12 @@
 package nebula_go
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"net"
 	"os/exec"
 	"sync"
 	"testing"
@@ -114,6 +118,16 @@ func TestConfigs(t *testing.T) {
 			MaxConnPoolSize: 10,
 			MinConnPoolSize: 1,
 		},
+		// MaxConnPoolSize = 0
+		{
+			TimeOut:         0 * time.Millisecond,
+			IdleTime:        0 * time.Millisecond,
+			MaxConnPoolSize: 0,
+			MinConnPoolSize: 1,
+		},
+	}
+
+	var invalidConfigList = []PoolConfig{
 		// timeout < 0
 		{
 			TimeOut:         -1 * time.Millisecond,
@@ -137,6 +151,11 @@ func TestConfigs(t *testing.T) {
 		},
 	}
 
+	for _, testPoolConfig := range invalidConfigList {
+		_, err := NewConnectionPool(hostList, testPoolConfig, nebulaLog)
+		assert.NotNil(t, err)
+	}
+
 	for _, testPoolConfig := range configList {
 		// Initialize connectin pool
 		pool, err := NewConnectionPool(hostList, testPoolConfig, nebulaLog)
@@ -198,6 +217,10 @@ func TestAuthentication(t *testing.T) {
 
 	_, authErr := conn.authenticate(username, password)
 	assert.EqualError(t, authErr, "fail to authenticate, error: Bad username/password")
+	var typedErr *AuthError
+	if assert.True(t, errors.As(authErr, &typedErr)) {
+		assert.Equal(t, ErrorCode_E_BAD_USERNAME_PASSWORD, typedErr.Code)
+	}
 }
 
 func TestInvalidHostTimeout(t *testing.T) {
@@ -924,6 +947,230 @@ func TestReconnect(t *testing.T) {
 	pool.Close()
 }
 
+func TestExecuteWithContext(t *testing.T) {
+	hostList := []HostAddress{{Host: address, Port: port}}
+
+	// Initialize connectin pool
+	pool, err := NewConnectionPool(hostList, GetDefaultConf(), nebulaLog)
+	if err != nil {
+		t.Fatalf("fail to initialize the connection pool, host: %s, port: %d, %s", address, port, err.Error())
+	}
+	defer pool.Close()
+
+	session, err := pool.GetSession(username, password)
+	if err != nil {
+		t.Fatalf("fail to create a new session from connection pool, %s", err.Error())
+	}
+	defer session.Release()
+
+	// Cancelled context
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = session.ExecuteWithContext(ctx, "SHOW HOSTS;")
+	assert.True(t, errors.Is(err, context.Canceled))
+
+	// The session is still usable after an aborted query
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resp, err := session.ExecuteWithContext(ctx, "SHOW HOSTS;")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	checkResSetResp(t, "show hosts", resp)
+
+	// JSON
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	_, err = session.ExecuteJsonWithContext(ctx, "SHOW HOSTS;")
+	assert.True(t, errors.Is(err, context.Canceled))
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	jsonResp, err := session.ExecuteJsonWithContext(ctx, "SHOW HOSTS;")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	assert.True(t, json.Valid(jsonResp))
+}
+
+func TestCustomDialer(t *testing.T) {
+	hostList := []HostAddress{{Host: address, Port: port}}
+
+	var dialed int
+	conf := GetDefaultConf()
+	conf.MinConnPoolSize = 1
+	conf.Dialer = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dialed++
+		var d net.Dialer
+		return d.DialContext(ctx, network, addr)
+	}
+
+	// Initialize connectin pool
+	pool, err := NewConnectionPool(hostList, conf, nebulaLog)
+	if err != nil {
+		t.Fatalf("fail to initialize the connection pool, host: %s, port: %d, %s", address, port, err.Error())
+	}
+	defer pool.Close()
+	assert.Equal(t, 1, dialed)
+
+	session, err := pool.GetSession(username, password)
+	if err != nil {
+		t.Fatalf("fail to create a new session from connection pool, %s", err.Error())
+	}
+	defer session.Release()
+
+	resp, err := session.Execute("SHOW HOSTS;")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	checkResSetResp(t, "show hosts", resp)
+}
+
+func TestExecuteBatch(t *testing.T) {
+	hostList := []HostAddress{{Host: address, Port: port}}
+
+	// Initialize connectin pool
+	pool, err := NewConnectionPool(hostList, GetDefaultConf(), nebulaLog)
+	if err != nil {
+		t.Fatalf("fail to initialize the connection pool, host: %s, port: %d, %s", address, port, err.Error())
+	}
+	defer pool.Close()
+
+	session, err := pool.GetSession(username, password)
+	if err != nil {
+		t.Fatalf("fail to create a new session from connection pool, %s", err.Error())
+	}
+	defer session.Release()
+
+	results, err := session.ExecuteBatch([]string{"YIELD 1", "YIELD", "YIELD 2"})
+	assert.Equal(t, 3, len(results))
+	var batchErr *BatchError
+	if assert.True(t, errors.As(err, &batchErr)) {
+		assert.Equal(t, []int{1}, batchErr.FailedIndexes())
+	}
+	assert.True(t, results[0].IsSucceed())
+	assert.Equal(t, ErrorCode_E_SYNTAX_ERROR, results[1].GetErrorCode())
+	assert.True(t, results[2].IsSucceed())
+}
+
+func TestShutdown(t *testing.T) {
+	hostList := []HostAddress{{Host: address, Port: port}}
+
+	// Initialize connectin pool
+	pool, err := NewConnectionPool(hostList, GetDefaultConf(), nebulaLog)
+	if err != nil {
+		t.Fatalf("fail to initialize the connection pool, host: %s, port: %d, %s", address, port, err.Error())
+	}
+	defer pool.Close()
+
+	session, err := pool.GetSession(username, password)
+	if err != nil {
+		t.Fatalf("fail to create a new session from connection pool, %s", err.Error())
+	}
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		session.Release()
+	}()
+
+	// Wait for the session to be released
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.Nil(t, pool.Shutdown(ctx))
+	assert.Equal(t, 0, pool.getActiveConnCount()+pool.getIdleConnCount())
+
+	// No more session after shutdown
+	_, err = pool.GetSession(username, password)
+	assert.NotNil(t, err)
+}
+
+func TestExecuteAndCheck(t *testing.T) {
+	hostList := []HostAddress{{Host: address, Port: port}}
+
+	// Initialize connectin pool
+	pool, err := NewConnectionPool(hostList, GetDefaultConf(), nebulaLog)
+	if err != nil {
+		t.Fatalf("fail to initialize the connection pool, host: %s, port: %d, %s", address, port, err.Error())
+	}
+	defer pool.Close()
+
+	session, err := pool.GetSession(username, password)
+	if err != nil {
+		t.Fatalf("fail to create a new session from connection pool, %s", err.Error())
+	}
+	defer session.Release()
+
+	resp, err := session.ExecuteAndCheck("YIELD 1")
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	assert.True(t, resp.IsSucceed())
+
+	resp, err = session.ExecuteAndCheck("YIELD")
+	assert.Nil(t, resp)
+	assert.NotNil(t, err)
+}
+
+func TestWithSession(t *testing.T) {
+	hostList := []HostAddress{{Host: address, Port: port}}
+
+	// Initialize connectin pool
+	pool, err := NewConnectionPool(hostList, GetDefaultConf(), nebulaLog)
+	if err != nil {
+		t.Fatalf("fail to initialize the connection pool, host: %s, port: %d, %s", address, port, err.Error())
+	}
+	defer pool.Close()
+
+	err = pool.WithSession(username, password, func(session *Session) error {
+		_, err := session.ExecuteAndCheck("YIELD 1")
+		return err
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 0, pool.getActiveConnCount())
+
+	// The session is released on panic
+	assert.Panics(t, func() {
+		pool.WithSession(username, password, func(session *Session) error {
+			panic("test panic")
+		})
+	})
+	assert.Equal(t, 0, pool.getActiveConnCount())
+}
+
+func TestHooks(t *testing.T) {
+	hostList := []HostAddress{{Host: address, Port: port}}
+
+	var started, ended, failed, acquired int
+	conf := GetDefaultConf()
+	conf.Hooks = Hooks{
+		OnQueryStart: func(stmt string) { started++ },
+		OnQueryEnd: func(stmt string, latency time.Duration, err error) {
+			ended++
+			if err != nil {
+				failed++
+			}
+		},
+		OnConnAcquire: func(waited time.Duration) { acquired++ },
+	}
+	// Initialize connectin pool
+	pool, err := NewConnectionPool(hostList, conf, nebulaLog)
+	if err != nil {
+		t.Fatalf("fail to initialize the connection pool, host: %s, port: %d, %s", address, port, err.Error())
+	}
+	defer pool.Close()
+
+	session, err := pool.GetSession(username, password)
+	if err != nil {
+		t.Fatalf("fail to create a new session from connection pool, %s", err.Error())
+	}
+	defer session.Release()
+
+	session.Execute("YIELD 1")
+	session.Execute("YIELD")
+	assert.Equal(t, 2, started)
+	assert.Equal(t, 2, ended)
+	assert.Equal(t, 1, failed)
+	assert.Equal(t, 1, acquired)
+}
+
 func TestIpLookup(t *testing.T) {
 	hostAddress := HostAddress{Host: "192.168.10.105", Port: 3699}
 	hostList := []HostAddress{hostAddress}
@@ -971,3 +1218,53 @@ func tryToExecute(session *Session, query string) (resp *ResultSet, err error) {
 	}
 	return
 }
+
+func TestSpaceName(t *testing.T) {
+	hostList := []HostAddress{{Host: address, Port: port}}
+
+	// Initialize connectin pool
+	pool, err := NewConnectionPool(hostList, GetDefaultConf(), nebulaLog)
+	if err != nil {
+		t.Fatalf("fail to initialize the connection pool, host: %s, port: %d, %s", address, port, err.Error())
+	}
+	defer pool.Close()
+	err = pool.WithSession(username, password, func(session *Session) error {
+		_, err := session.ExecuteAndCheck("CREATE SPACE IF NOT EXISTS test_space_name(vid_type = FIXED_STRING(30));")
+		return err
+	})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	// Wait for the space to be created
+	time.Sleep(3 * time.Second)
+
+	conf := GetDefaultConf()
+	conf.SpaceName = "test_space_name"
+	spacePool, err := NewConnectionPool(hostList, conf, nebulaLog)
+	if err != nil {
+		t.Fatalf("fail to initialize the connection pool, host: %s, port: %d, %s", address, port, err.Error())
+	}
+	defer spacePool.Close()
+
+	session, err := spacePool.GetSession(username, password)
+	if err != nil {
+		t.Fatalf("fail to create a new session from connection pool, %s", err.Error())
+	}
+	assert.Equal(t, "test_space_name", session.CurrentSpace())
+	resp, err := session.ExecuteAndCheck("SHOW TAGS")
+	assert.Nil(t, err)
+	assert.Equal(t, "test_space_name", resp.GetSpaceName())
+	session.Release()
+
+	// Creating a session fails if the space cannot be used
+	conf.SpaceName = "not_exist_space"
+	badPool, err := NewConnectionPool(hostList, conf, nebulaLog)
+	if err != nil {
+		t.Fatalf("fail to initialize the connection pool, host: %s, port: %d, %s", address, port, err.Error())
+	}
+	defer badPool.Close()
+	_, err = badPool.GetSession(username, password)
+	var execErr *ExecutionError
+	assert.True(t, errors.As(err, &execErr))
+	assert.Equal(t, 0, badPool.getActiveConnCount())
+}
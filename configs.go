@@ -7,48 +7,419 @@
 package nebula_go
 
 import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"net"
+	"strings"
 	"time"
+
+	"github.com/facebook/fbthrift/thrift/lib/go/thrift"
 )
 
 type PoolConfig struct {
 	// Socket timeout and Socket connection timeout, unit: seconds
 	TimeOut time.Duration
+	// The timeout to establish a connection, unit: seconds
+	// 0 value means TimeOut is used
+	ConnTimeout time.Duration
+	// The max execution time of a query, unit: seconds
+	// It applies to the queries executed without a deadline, a deadline given by
+	// ExecuteWithContext or ExecuteWithTimeout takes precedence even if it is longer
+	// 0 value means the queries are only bounded by the socket timeout
+	QueryTimeout time.Duration
+	// The latency reported by the server from which a query is logged as slow with its statement,
+	// which is truncated to 512 bytes
+	// 0 value means slow queries are not logged
+	SlowQueryThreshold time.Duration
+	// The size of the buffer of the socket transport, unit: bytes
+	// 0 value means the default of 128KB
+	SocketBufferSize int
+	// The max size of a statement, unit: bytes
+	// Larger statements are rejected before they are sent, 0 value means unlimited
+	MaxStmtBytes int
+	// Whether the statements are checked with ValidateStatement before they are sent, so the obviously
+	// malformed statements fail without a round trip to the server
+	ValidateBeforeSend bool
+	// The max size of the response of a query, unit: bytes
+	// The query fails if its response is larger, 0 value means unlimited
+	MaxResultBytes int
+	// The max total size of the responses the sessions of the pool receive at the same time, unit: bytes
+	// A query reserves MaxResultBytes, the max size of its response, while it is executed. It waits for
+	// the reservations of the other queries to be released if the total would exceed the limit, until its
+	// context is done. It requires MaxResultBytes and must not be less than it, see PoolStats.ResultBytesInUse
+	// 0 value means unlimited
+	MaxTotalResultBytes int
 	// The idleTime of the connection, unit: seconds
 	// If connection's idle time is longer than idleTime, it will be delete
 	// 0 value means the connection will not expire
 	IdleTime time.Duration
+	// The max time a connection is used after it is opened, unit: seconds
+	// An expired connection is closed when it is returned to the pool or found idle, instead of being
+	// handed out, and a new connection is opened on demand, or on return if the pool would go below
+	// MinConnPoolSize. A connection in use by a session is not interrupted. IdleTime closes the connections unused for long, MaxConnLifetime the ones used for long
+	// 0 value means the connections are not recycled
+	MaxConnLifetime time.Duration
+	// The order in which the idle connections are handed out, IdleFIFO or IdleLIFO
+	// Empty value means IdleFIFO
+	IdleOrder IdleOrder
 	// The max connections in pool for all addresses
+	// 0 value means the default of 10
 	MaxConnPoolSize int
+	// The max connections in pool to a single address, the new connections spill to the other addresses
+	// when an address reaches it, and the pool is exhausted when all the addresses reach it
+	// 0 value means the connections to an address are only limited by MaxConnPoolSize
+	MaxConnsPerHost int
 	// The min connections in pool for all addresses
+	// These connections are opened when the pool is initialized, spread across the addresses
 	MinConnPoolSize int
+	// Whether the pool is initialized even if some of the min connections failed to be opened
+	// false value means the initialization fails if any of the min connections fails to be opened
+	WarmupBestEffort bool
+	// The idle time after which a connection is checked before it is handed out, unit: seconds
+	// A connection failing the check is reopened, 0 value means connections are always checked
+	MaxConnIdleBeforeCheck time.Duration
+	// The statement executed to check a connection, e.g. before it is handed out or by the keepalive check
+	// Empty value means the default of "YIELD 1"
+	HealthCheckStmt string
+	// The interval of the keepalive check on idle connections, unit: seconds
+	// Connections idle for longer than the interval are pinged and the ones failing the ping are removed
+	// 0 value means the keepalive check is disabled
+	KeepAliveInterval time.Duration
+	// Dialer is used to establish the TCP connections to the graph service if set,
+	// e.g. to connect through a proxy or to customize the socket options
+	// nil value means the connections are established with the default socket
+	Dialer func(ctx context.Context, network, addr string) (net.Conn, error)
+	// The local address the TCP connections to the graph service originate from, e.g. when a firewall
+	// only accepts a specific local IP. It must be a *net.TCPAddr, a 0 port means any port is used
+	// It is exclusive with Dialer, nil value means the local address is chosen by the system
+	LocalAddr net.Addr
+	// ProtocolFactory creates the thrift protocol of the connections if set, e.g. to wrap the protocol
+	// to trace the requests. The protocol must be compatible with the graph service
+	// nil value means the binary protocol is used
+	ProtocolFactory thrift.ProtocolFactory
+	// GraphServiceFactory creates the client of the graph service of each connection instead of opening
+	// a transport to the host if set, e.g. to inject the in-memory fake of the package fake in tests
+	// Dialer, ProtocolFactory and the ssl options are not used in this case
+	// nil value means the generated thrift client over the socket transport is used
+	GraphServiceFactory func(host HostAddress) (GraphService, error)
+	// Whether a session keeps the host of its connection when the connection is reconnected
+	// The connection is reopened to the same host, another host is used only if the host is down
+	// false value means a session is reconnected to the host of any connection of the pool
+	StickyHost bool
+	// The names to verify the ssl certificates of the hosts against, keyed by the host:port given to the pool,
+	// e.g. when the hosts are given by IP behind a VIP whose certificate is issued for a domain name
+	// A host missing in the map is verified against the ServerName of the ssl config, or its address if unset
+	SSLServerNames map[string]string
+	// The hex encoded SHA-256 fingerprints of the DER encoded certificates the hosts may present,
+	// the bytes may be separated by colons. A host whose leaf certificate matches none of them is
+	// rejected during the handshake, in addition to the verification of the ssl config
+	// Empty value means the certificates are not pinned
+	PinnedCertSHA256 []string
+	// The labels of the hosts, keyed by the host:port given to the pool, e.g. to isolate the traffic
+	// of tenants. ConnectionPool.GetSessionWithLabel creates sessions on the hosts carrying a label
+	// A host missing in the map carries no label
+	HostLabels map[string][]string
+	// The DNS name and the port of the graph services, e.g. a name whose records list the instances
+	// of the cluster. The addresses of all its A and AAAA records are used in addition to the addresses
+	// given to the pool, which may be empty. The certificates of these hosts are verified against
+	// the name, or against SSLServerNames of the name and the port if set
+	// Empty Host means no host is discovered
+	DNSHost HostAddress
+	// The interval at which DNSHost is resolved again, the new addresses are added and the removed ones
+	// are drained: their idle connections are closed and their active connections are closed when released
+	// 0 value means DNSHost is only resolved when the pool is created
+	DNSRefreshInterval time.Duration
+	// The backoff applied to a host after it failed to be connected
+	// Hosts in backoff are skipped when creating new connections
+	RetryPolicy RetryPolicy
+	// The circuit breaker skipping a host entirely after repeated connection failures
+	// 0 value of CircuitBreaker.FailureThreshold means the breaker is disabled
+	CircuitBreaker CircuitBreaker
+	// The strategy to select the host when creating new connections, e.g. &RoundRobin{} or LeastConnections{}
+	// nil value means the hosts are selected with round robin
+	LoadBalancer LoadBalancer
+	// The strategy to select the host of a session by its affinity key, see GetSessionWithAffinity
+	// nil value means the hosts are selected with RendezvousHash
+	AffinityHasher AffinityHasher
+	// Whether a session re-authenticates and retries the query once when the server reports it expired
+	// The credentials are kept in the session in this case, false by default since a silent
+	// re-authentication can mask real problems, see Session.ReauthCount
+	ReauthOnSessionExpired bool
+	// The max times a query is executed again when it failed with a leader change or a transient
+	// storage error, and the delay before each of these retries
+	// 0 value means the query is never retried, syntax or permission errors are never retried
+	LeaderChangeRetries    int
+	LeaderChangeRetryDelay time.Duration
+	// The max times a query executed by Session.ExecuteIdempotent is executed again on another host
+	// when it failed with a transport error
+	// 0 value means the query is never failed over, queries executed by Session.Execute are never failed over
+	// unless FailoverReadOnly is set
+	FailoverRetries int
+	// Whether the queries executed by Session.Execute and Session.ExecuteWithContext are failed over
+	// as with Session.ExecuteIdempotent when ReadOnlyClassifier classifies them as read-only
+	FailoverReadOnly bool
+	// The classifier of the read-only statements for FailoverReadOnly
+	// nil value means IsReadOnlyStmt
+	ReadOnlyClassifier func(stmt string) bool
+	// Whether a query whose connection was closed while it was executed is returned as an error
+	// instead of being executed again on a reopened connection, e.g. to decide whether to retry
+	// writes which are not idempotent. The connection is still reopened for the next query
+	DisableAutoReopen bool
+	// The space every session uses, e.g. when the pool is dedicated to a single space
+	// USE is executed right after a session is authenticated or re-authenticated and after its connection
+	// is reconnected or failed over, creating the session fails if the space cannot be used
+	// Empty value means sessions use no space until USE is executed
+	SpaceName string
+	// The callbacks invoked on query execution and connection acquisition, e.g. to collect metrics
+	Hooks Hooks
+	// The tracer starting a span for each query, see Tracer
+	// nil value means the queries are not traced
+	Tracer Tracer
+	// Whether the literals of the statements written in the logs and given to the Tracer are masked
+	// by RedactLiterals, e.g. when the values are personal data. The queries are sent unchanged
+	// The messages of the server, e.g. of a syntax error, are not redacted
+	RedactLiterals bool
+	// The function redacting the statements written in the logs and given to the Tracer,
+	// it takes precedence over RedactLiterals
+	// nil value means the statements are logged and traced as they are unless RedactLiterals is set
+	RedactStmt func(stmt string) string
+	// The logger receiving the events of the pool and its sessions with their key-value pairs,
+	// it takes precedence over the Logger given to the pool, e.g. StdLogger{}
+	// nil value means the Logger given to the pool is used
+	Logger StructuredLogger
+}
+
+// Hooks are the callbacks invoked by the pool and its sessions, nil callbacks are skipped.
+// They are called synchronously on the path of the query, so they should return quickly.
+type Hooks struct {
+	// Called before a query is sent
+	OnQueryStart func(stmt string)
+	// Called after a query completed, err is the error returned by the execution,
+	// or an error holding the error code and message of the server if the query is not succeeded
+	OnQueryEnd func(stmt string, latency time.Duration, err error)
+	// Called after a session acquired a connection with the time spent acquiring it
+	OnConnAcquire func(waited time.Duration)
 }
 
-// Validate config
-func (conf *PoolConfig) validateConf(log Logger) {
-	if conf.TimeOut < 0 {
-		conf.TimeOut = 0 * time.Millisecond
-		log.Warn("Illegal Timeout value, the default value of 0 second has been applied")
+func (hooks Hooks) queryStart(stmt string) {
+	if hooks.OnQueryStart != nil {
+		hooks.OnQueryStart(stmt)
 	}
-	if conf.IdleTime < 0 {
-		conf.IdleTime = 0 * time.Millisecond
-		log.Warn("Invalid IdleTime value, the default value of 0 second has been applied")
+}
+
+func (hooks Hooks) connAcquire(waited time.Duration) {
+	if hooks.OnConnAcquire != nil {
+		hooks.OnConnAcquire(waited)
 	}
-	if conf.MaxConnPoolSize < 1 {
-		conf.MaxConnPoolSize = 10
-		log.Warn("Invalid MaxConnPoolSize value, the default value of 10 has been applied")
+}
+
+// RetryPolicy is the exponential backoff applied to a host after consecutive connection failures.
+// The delay after n consecutive failures is InitialDelay * Multiplier^(n-1) capped by MaxDelay,
+// half of the delay is randomized to avoid reconnecting to a recovered host all at once.
+type RetryPolicy struct {
+	// The delay after the first failure
+	// 0 value means hosts are never put in backoff
+	InitialDelay time.Duration
+	// The max delay after consecutive failures
+	// 0 value means the delay is InitialDelay after any number of failures
+	MaxDelay time.Duration
+	// The factor applied to the delay after each consecutive failure
+	// 0 value means the default of 2
+	Multiplier float64
+}
+
+// CircuitBreaker opens the breaker of a host after FailureThreshold consecutive connection failures
+// within Window. While the breaker is open, the host is skipped entirely for Cooldown: neither new
+// nor idle connections to it are used. The breaker is then half-open and a single connection attempt
+// to the host is allowed as a trial, which closes the breaker if it succeeds or opens it again for
+// Cooldown if it fails. The state of the breakers is reported by ConnectionPool.Stats.
+type CircuitBreaker struct {
+	// The number of consecutive connection failures within Window opening the breaker
+	// 0 value means the breaker is disabled
+	FailureThreshold int
+	// The window in which the consecutive failures are counted from the first one
+	// 0 value means the default of 1 minute
+	Window time.Duration
+	// The time the host is skipped after the breaker opened
+	// 0 value means the default of 30 seconds
+	Cooldown time.Duration
+}
+
+// IdleOrder is the order in which the idle connections of a pool are handed out
+type IdleOrder string
+
+const (
+	// The connection idle for the longest is handed out first, so all the connections are used in turn
+	// and kept warm under a steady load
+	IdleFIFO IdleOrder = "fifo"
+	// The connection returned last is handed out first, so the connections left unused after a burst
+	// stay idle and are closed after IdleTime, reducing the connections to the current load
+	IdleLIFO IdleOrder = "lifo"
+)
+
+// Return the backoff delay after the given number of consecutive failures
+func (policy RetryPolicy) delay(failures int) time.Duration {
+	if policy.InitialDelay <= 0 || failures <= 0 {
+		return 0
+	}
+	d := float64(policy.InitialDelay) * math.Pow(policy.Multiplier, float64(failures-1))
+	if d > float64(policy.MaxDelay) {
+		d = float64(policy.MaxDelay)
+	}
+	half := time.Duration(d / 2)
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// Validate checks the config and applies the defaults to the zero-valued fields:
+//
+//	SocketBufferSize: 128KB
+//	MaxConnPoolSize: 10
+//	HealthCheckStmt: "YIELD 1"
+//	RetryPolicy.MaxDelay: RetryPolicy.InitialDelay
+//	RetryPolicy.Multiplier: 2
+//	CircuitBreaker.Window: 1m
+//	CircuitBreaker.Cooldown: 30s
+//	LoadBalancer: &RoundRobin{}
+//	AffinityHasher: RendezvousHash{}
+//
+// An error naming the invalid field is returned if a field is negative or the fields contradict.
+// It is called when a connection pool is created.
+func (conf *PoolConfig) Validate() error {
+	durations := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"TimeOut", conf.TimeOut},
+		{"ConnTimeout", conf.ConnTimeout},
+		{"QueryTimeout", conf.QueryTimeout},
+		{"SlowQueryThreshold", conf.SlowQueryThreshold},
+		{"IdleTime", conf.IdleTime},
+		{"MaxConnLifetime", conf.MaxConnLifetime},
+		{"MaxConnIdleBeforeCheck", conf.MaxConnIdleBeforeCheck},
+		{"KeepAliveInterval", conf.KeepAliveInterval},
+		{"RetryPolicy.InitialDelay", conf.RetryPolicy.InitialDelay},
+		{"RetryPolicy.MaxDelay", conf.RetryPolicy.MaxDelay},
+		{"CircuitBreaker.Window", conf.CircuitBreaker.Window},
+		{"CircuitBreaker.Cooldown", conf.CircuitBreaker.Cooldown},
+		{"LeaderChangeRetryDelay", conf.LeaderChangeRetryDelay},
+		{"DNSRefreshInterval", conf.DNSRefreshInterval},
+	}
+	for _, d := range durations {
+		if d.value < 0 {
+			return fmt.Errorf("invalid %s value %v: must not be negative", d.name, d.value)
+		}
+	}
+	if conf.MaxStmtBytes < 0 {
+		return fmt.Errorf("invalid MaxStmtBytes value %d: must not be negative", conf.MaxStmtBytes)
+	}
+	if conf.MaxResultBytes < 0 || int64(conf.MaxResultBytes) > math.MaxUint32 {
+		return fmt.Errorf("invalid MaxResultBytes value %d: must be between 0 and %d", conf.MaxResultBytes, uint32(math.MaxUint32))
+	}
+	if conf.MaxTotalResultBytes < 0 {
+		return fmt.Errorf("invalid MaxTotalResultBytes value %d: must not be negative", conf.MaxTotalResultBytes)
+	}
+	if conf.MaxTotalResultBytes > 0 && (conf.MaxResultBytes == 0 || conf.MaxTotalResultBytes < conf.MaxResultBytes) {
+		return fmt.Errorf("invalid MaxTotalResultBytes value %d: requires MaxResultBytes and must not be less than it",
+			conf.MaxTotalResultBytes)
+	}
+	if conf.SocketBufferSize < 0 {
+		return fmt.Errorf("invalid SocketBufferSize value %d: must not be negative", conf.SocketBufferSize)
+	}
+	if conf.MaxConnPoolSize < 0 {
+		return fmt.Errorf("invalid MaxConnPoolSize value %d: must not be negative", conf.MaxConnPoolSize)
+	}
+	if conf.MaxConnsPerHost < 0 {
+		return fmt.Errorf("invalid MaxConnsPerHost value %d: must not be negative", conf.MaxConnsPerHost)
 	}
 	if conf.MinConnPoolSize < 0 {
-		conf.MinConnPoolSize = 0
-		log.Warn("Invalid MinConnPoolSize value, the default value of 0 has been applied")
+		return fmt.Errorf("invalid MinConnPoolSize value %d: must not be negative", conf.MinConnPoolSize)
+	}
+	if conf.LeaderChangeRetries < 0 {
+		return fmt.Errorf("invalid LeaderChangeRetries value %d: must not be negative", conf.LeaderChangeRetries)
+	}
+	if conf.FailoverRetries < 0 {
+		return fmt.Errorf("invalid FailoverRetries value %d: must not be negative", conf.FailoverRetries)
+	}
+	if conf.DNSHost.Host != "" && conf.DNSHost.Port <= 0 {
+		return fmt.Errorf("invalid DNSHost value %s: the port must be positive", conf.DNSHost.String())
+	}
+	if conf.DNSRefreshInterval > 0 && conf.DNSHost.Host == "" {
+		return fmt.Errorf("invalid DNSRefreshInterval value %v: requires DNSHost", conf.DNSRefreshInterval)
+	}
+	if strings.ContainsAny(conf.SpaceName, "`\n") {
+		return fmt.Errorf("invalid SpaceName value %q: must not contain a backtick or a line break", conf.SpaceName)
+	}
+	if conf.LocalAddr != nil {
+		if _, ok := conf.LocalAddr.(*net.TCPAddr); !ok {
+			return fmt.Errorf("invalid LocalAddr value %v: must be a *net.TCPAddr", conf.LocalAddr)
+		}
+		if conf.Dialer != nil {
+			return fmt.Errorf("invalid LocalAddr value %v: must not be set with Dialer", conf.LocalAddr)
+		}
+	}
+	if conf.IdleOrder != "" && conf.IdleOrder != IdleFIFO && conf.IdleOrder != IdleLIFO {
+		return fmt.Errorf("invalid IdleOrder value %q: must be %q or %q", conf.IdleOrder, IdleFIFO, IdleLIFO)
+	}
+	if conf.CircuitBreaker.FailureThreshold < 0 {
+		return fmt.Errorf("invalid CircuitBreaker.FailureThreshold value %d: must not be negative",
+			conf.CircuitBreaker.FailureThreshold)
+	}
+	for _, pin := range conf.PinnedCertSHA256 {
+		if _, err := parseCertPin(pin); err != nil {
+			return fmt.Errorf("invalid PinnedCertSHA256 value: %w", err)
+		}
+	}
+	if conf.RetryPolicy.Multiplier < 0 || (conf.RetryPolicy.Multiplier > 0 && conf.RetryPolicy.Multiplier < 1) {
+		return fmt.Errorf("invalid RetryPolicy.Multiplier value %v: must not be less than 1", conf.RetryPolicy.Multiplier)
+	}
+	if conf.RetryPolicy.MaxDelay > 0 && conf.RetryPolicy.MaxDelay < conf.RetryPolicy.InitialDelay {
+		return fmt.Errorf("invalid RetryPolicy.MaxDelay value %v: must not be less than RetryPolicy.InitialDelay %v",
+			conf.RetryPolicy.MaxDelay, conf.RetryPolicy.InitialDelay)
+	}
+
+	if conf.SocketBufferSize == 0 {
+		conf.SocketBufferSize = defaultSocketBufferSize
+	}
+	if conf.MaxConnPoolSize == 0 {
+		conf.MaxConnPoolSize = 10
+	}
+	if conf.MinConnPoolSize > conf.MaxConnPoolSize {
+		return fmt.Errorf("invalid MinConnPoolSize value %d: must not be greater than MaxConnPoolSize %d",
+			conf.MinConnPoolSize, conf.MaxConnPoolSize)
+	}
+	if conf.HealthCheckStmt == "" {
+		conf.HealthCheckStmt = defaultHealthCheckStmt
+	}
+	if conf.RetryPolicy.MaxDelay == 0 {
+		conf.RetryPolicy.MaxDelay = conf.RetryPolicy.InitialDelay
+	}
+	if conf.RetryPolicy.Multiplier == 0 {
+		conf.RetryPolicy.Multiplier = 2
+	}
+	if conf.CircuitBreaker.Window == 0 {
+		conf.CircuitBreaker.Window = time.Minute
+	}
+	if conf.CircuitBreaker.Cooldown == 0 {
+		conf.CircuitBreaker.Cooldown = 30 * time.Second
+	}
+	if conf.LoadBalancer == nil {
+		conf.LoadBalancer = &RoundRobin{}
+	}
+	if conf.AffinityHasher == nil {
+		conf.AffinityHasher = RendezvousHash{}
 	}
+	return nil
 }
 
 // Return the default config
 func GetDefaultConf() PoolConfig {
 	return PoolConfig{
-		TimeOut:         0 * time.Millisecond,
-		IdleTime:        0 * time.Millisecond,
-		MaxConnPoolSize: 10,
-		MinConnPoolSize: 0,
+		TimeOut:           0 * time.Millisecond,
+		IdleTime:          0 * time.Millisecond,
+		MaxConnPoolSize:   10,
+		MinConnPoolSize:   0,
+		KeepAliveInterval: 0 * time.Millisecond,
 	}
 }
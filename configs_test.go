@@ -0,0 +1,87 @@
+/* Copyright (c) 2021 vesoft inc. All rights reserved.
+ *
+ * This source code is licensed under Apache 2.0 License,
+ * attached with Common Clause Condition 1.0, found in the LICENSES directory.
+ */
+
+package nebula_go
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	policy := RetryPolicy{
+		InitialDelay: 100 * time.Millisecond,
+		MaxDelay:     time.Second,
+		Multiplier:   2,
+	}
+	assert.Equal(t, time.Duration(0), policy.delay(0))
+
+	// Half of the delay is randomized
+	d := policy.delay(1)
+	assert.True(t, d >= 50*time.Millisecond && d <= 100*time.Millisecond)
+	d = policy.delay(3)
+	assert.True(t, d >= 200*time.Millisecond && d <= 400*time.Millisecond)
+	// Capped by MaxDelay
+	d = policy.delay(100)
+	assert.True(t, d >= 500*time.Millisecond && d <= time.Second)
+
+	// Disabled
+	assert.Equal(t, time.Duration(0), RetryPolicy{}.delay(3))
+}
+
+func TestPoolConfigValidate(t *testing.T) {
+	conf := PoolConfig{RetryPolicy: RetryPolicy{InitialDelay: time.Second}}
+	assert.Nil(t, conf.Validate())
+	assert.Equal(t, 128<<10, conf.SocketBufferSize)
+	assert.Equal(t, 10, conf.MaxConnPoolSize)
+	assert.Equal(t, "YIELD 1", conf.HealthCheckStmt)
+	assert.Equal(t, time.Second, conf.RetryPolicy.MaxDelay)
+	assert.Equal(t, 2.0, conf.RetryPolicy.Multiplier)
+	assert.Equal(t, time.Minute, conf.CircuitBreaker.Window)
+	assert.Equal(t, 30*time.Second, conf.CircuitBreaker.Cooldown)
+	assert.Equal(t, &RoundRobin{}, conf.LoadBalancer)
+	assert.Equal(t, RendezvousHash{}, conf.AffinityHasher)
+
+	invalidConfs := []PoolConfig{
+		{TimeOut: -1},
+		{IdleTime: -1},
+		{ConnTimeout: -1},
+		{QueryTimeout: -1},
+		{SlowQueryThreshold: -1},
+		{SocketBufferSize: -1},
+		{MaxStmtBytes: -1},
+		{MaxResultBytes: -1},
+		{MaxTotalResultBytes: -1},
+		{MaxTotalResultBytes: 1 << 20},
+		{MaxTotalResultBytes: 1 << 20, MaxResultBytes: 2 << 20},
+		{MaxConnPoolSize: -1},
+		{MaxConnsPerHost: -1},
+		{IdleOrder: "random"},
+		{DNSHost: HostAddress{Host: "graphd.nebula"}},
+		{DNSRefreshInterval: time.Minute},
+		{DNSHost: HostAddress{"graphd.nebula", 9669}, DNSRefreshInterval: -1},
+		{MinConnPoolSize: -1},
+		{MaxConnPoolSize: 1, MinConnPoolSize: 2},
+		{KeepAliveInterval: -1},
+		{RetryPolicy: RetryPolicy{InitialDelay: 2 * time.Second, MaxDelay: time.Second}},
+		{RetryPolicy: RetryPolicy{Multiplier: 0.5}},
+		{CircuitBreaker: CircuitBreaker{FailureThreshold: -1}},
+		{CircuitBreaker: CircuitBreaker{Cooldown: -1}},
+		{SpaceName: "a`b"},
+		{LocalAddr: &net.UDPAddr{}},
+		{LocalAddr: &net.TCPAddr{}, Dialer: (&net.Dialer{}).DialContext},
+		{LeaderChangeRetries: -1},
+		{FailoverRetries: -1},
+		{MaxConnLifetime: -1},
+		{PinnedCertSHA256: []string{"not hex"}},
+	}
+	for _, conf := range invalidConfs {
+		assert.NotNil(t, conf.Validate())
+	}
+}
@@ -7,8 +7,14 @@
 package nebula_go
 
 import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"math"
+	"net"
+	"strings"
 	"time"
 
 	"github.com/facebook/fbthrift/thrift/lib/go/thrift"
@@ -16,10 +22,54 @@ import (
 	"github.com/vesoft-inc/nebula-go/v2/nebula/graph"
 )
 
+const (
+	// The default size of the buffer of the socket transport
+	defaultSocketBufferSize = 128 << 10
+	// The default statement to check a connection
+	defaultHealthCheckStmt = "YIELD 1"
+)
+
+// GraphService is the client of the graph service a connection sends its requests with.
+// By default it is the generated thrift client over the socket transport of the connection,
+// another implementation can be injected with PoolConfig.GraphServiceFactory, e.g. the in-memory
+// fake of the package fake to test code using the client without a graph service.
+type GraphService interface {
+	Authenticate(username []byte, password []byte) (*graph.AuthResponse, error)
+	Signout(sessionId int64) error
+	Execute(sessionId int64, stmt []byte) (*graph.ExecutionResponse, error)
+	ExecuteJson(sessionId int64, stmt []byte) ([]byte, error)
+	// Close releases the resources of the client, the connection creates a new client to reopen
+	Close() error
+}
+
+// socket is the net.Conn-backed transport of a connection, either plain or ssl
+type socket interface {
+	thrift.Transport
+	SetTimeout(timeout time.Duration) error
+	Interrupt() error
+	Conn() net.Conn
+}
+
 type connection struct {
 	severAddress HostAddress
-	returnedAt   time.Time // the connection was created or returned.
-	graph        *graph.GraphServiceClient
+	timeout      time.Duration
+	connTimeout  time.Duration // timeout to establish the transport, timeout is used if 0
+	returnedAt   time.Time     // the connection was created or returned.
+	openedAt     time.Time     // the transport was opened, the connection is recycled after the max lifetime
+	dialer       func(ctx context.Context, network, addr string) (net.Conn, error)
+	protocol     thrift.ProtocolFactory // the binary protocol is used if nil
+	sslConfig    *tls.Config
+	serverName   string // name to verify the certificate of the host against, overrides the one of sslConfig
+	bufferSize   int    // size of the buffer of the socket transport, defaultSocketBufferSize if 0
+	checkStmt    string // statement to check the connection, defaultHealthCheckStmt if empty
+	maxResult    int    // max size of a response in bytes, 0 means unlimited
+	sock         socket // nil if the client is created by newService
+	graph        GraphService
+	certPins     [][sha256.Size]byte // fingerprints the certificate of the host must match one of if not empty
+	// Creates the client instead of opening a transport if set
+	newService func(host HostAddress) (GraphService, error)
+	// The features of the host probed by Session.Capabilities, nil until probed
+	capabilities *ServerCapabilities
 }
 
 func newConnection(severAddress HostAddress) *connection {
@@ -31,60 +81,347 @@ func newConnection(severAddress HostAddress) *connection {
 }
 
 func (cn *connection) open(hostAddress HostAddress, timeout time.Duration) error {
-	ip := hostAddress.Host
-	port := hostAddress.Port
-	newAdd := fmt.Sprintf("%s:%d", ip, port)
-	timeoutOption := thrift.SocketTimeout(timeout)
-	bufferSize := 128 << 10
+	return cn.openWithContext(context.Background(), hostAddress, timeout)
+}
+
+// openWithContext opens the connection as open, the transport must be established before the deadline
+// of ctx. A cancellation of ctx aborts the dial and the ssl handshake.
+func (cn *connection) openWithContext(ctx context.Context, hostAddress HostAddress, timeout time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to open connection: %w", err)
+	}
+	if cn.newService != nil {
+		service, err := cn.newService(hostAddress)
+		if err != nil {
+			return &TransportError{Msg: fmt.Sprintf("failed to create graph service client, error: %s", err.Error()), Err: err}
+		}
+		cn.timeout = timeout
+		cn.graph = service
+		cn.openedAt = time.Now()
+		return nil
+	}
+	// JoinHostPort brackets IPv6 literals
+	newAdd := hostAddress.String()
+	bufferSize := cn.bufferSize
+	if bufferSize <= 0 {
+		bufferSize = defaultSocketBufferSize
+	}
 	frameMaxLength := uint32(math.MaxUint32)
-	addressOption := thrift.SocketAddr(newAdd)
-	sock, err := thrift.NewSocket(timeoutOption, addressOption)
+	if cn.maxResult > 0 {
+		frameMaxLength = uint32(cn.maxResult)
+	}
+	connTimeout := timeout
+	if cn.connTimeout > 0 {
+		connTimeout = cn.connTimeout
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if remaining := time.Until(deadline); connTimeout <= 0 || remaining < connTimeout {
+			connTimeout = remaining
+		}
+	}
+	sock, err := cn.newSocket(ctx, newAdd, connTimeout)
 	if err != nil {
-		return fmt.Errorf("failed to create a net.Conn-backed Transport,: %s", err.Error())
+		return err
 	}
+	cn.timeout = timeout
+	cn.sock = sock
 	// Set transport buffer
 	bufferedTranFactory := thrift.NewBufferedTransportFactory(bufferSize)
 	transport := thrift.NewFramedTransportMaxLength(bufferedTranFactory.GetTransport(sock), frameMaxLength)
-	pf := thrift.NewBinaryProtocolFactoryDefault()
-	cn.graph = graph.NewGraphServiceClientFactory(transport, pf)
-	if err = cn.graph.Open(); err != nil {
-		return fmt.Errorf("failed to open transport, error: %s", err.Error())
+	pf := cn.protocol
+	if pf == nil {
+		pf = thrift.NewBinaryProtocolFactoryDefault()
+	}
+	client := graph.NewGraphServiceClientFactory(transport, pf)
+	cn.graph = client
+	if !client.IsOpen() {
+		if err = client.Open(); err != nil {
+			return &TransportError{Msg: fmt.Sprintf("failed to open transport, error: %s", err.Error()), Err: err}
+		}
 	}
-	if !cn.graph.IsOpen() {
-		return fmt.Errorf("transport is off")
+	if !client.IsOpen() {
+		return &TransportError{Msg: "transport is off"}
 	}
+	// The transport is established, apply the socket timeout to the requests
+	sock.SetTimeout(timeout)
+	cn.openedAt = time.Now()
 	return nil
 }
 
+// Create the socket to addr, using ssl if sslConfig is set.
+// The socket is already open as the connection is established by dial.
+func (cn *connection) newSocket(ctx context.Context, addr string, timeout time.Duration) (socket, error) {
+	conn, err := cn.dial(ctx, addr, timeout)
+	if err != nil {
+		return nil, &TransportError{Msg: fmt.Sprintf("failed to open transport, error: %s", err.Error()), Err: err}
+	}
+	if cn.sslConfig != nil {
+		return thrift.NewSSLSocketFromConnTimeout(conn, cn.tlsConfig(), timeout), nil
+	}
+	sock, err := thrift.NewSocket(thrift.SocketTimeout(timeout), thrift.SocketConn(conn))
+	if err != nil {
+		conn.Close()
+		return nil, &TransportError{Msg: fmt.Sprintf("failed to create a net.Conn-backed Transport,: %s", err.Error()), Err: err}
+	}
+	return sock, nil
+}
+
+// Dial the address with the custom dialer, or a net.Dialer if not set, and perform the tls handshake
+// if sslConfig is set. The dial and the handshake are bounded by timeout if timeout > 0.
+func (cn *connection) dial(ctx context.Context, addr string, timeout time.Duration) (net.Conn, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	dialer := cn.dialer
+	if dialer == nil {
+		dialer = (&net.Dialer{Timeout: timeout}).DialContext
+	}
+	conn, err := dialer(ctx, "tcp", addr)
+	if err != nil || cn.sslConfig == nil {
+		return conn, err
+	}
+
+	config := cn.tlsConfig()
+	if config.ServerName == "" {
+		// Verify the certificate against the dialed host as tls.Dial does
+		host, _, _ := net.SplitHostPort(addr)
+		config = config.Clone()
+		config.ServerName = host
+	}
+	tlsConn := tls.Client(conn, config)
+	if deadline, ok := ctx.Deadline(); ok {
+		tlsConn.SetDeadline(deadline)
+	}
+	if ctx.Done() == nil {
+		err = tlsConn.Handshake()
+	} else {
+		// The handshake is interrupted by expiring the deadline of the connection once ctx is done
+		done := make(chan struct{})
+		interrupted := make(chan bool, 1)
+		go func() {
+			select {
+			case <-ctx.Done():
+				conn.SetDeadline(time.Now())
+				interrupted <- true
+			case <-done:
+				interrupted <- false
+			}
+		}()
+		err = tlsConn.Handshake()
+		close(done)
+		if <-interrupted {
+			err = ctx.Err()
+		}
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	tlsConn.SetDeadline(time.Time{})
+	return tlsConn, nil
+}
+
+// Return the ssl config with the server name of the host and the certificate pinning if set
+func (cn *connection) tlsConfig() *tls.Config {
+	if cn.serverName == "" && len(cn.certPins) == 0 {
+		return cn.sslConfig
+	}
+	config := cn.sslConfig.Clone()
+	if cn.serverName != "" {
+		config.ServerName = cn.serverName
+	}
+	if pins := cn.certPins; len(pins) > 0 {
+		verify := config.VerifyPeerCertificate
+		config.VerifyPeerCertificate = func(rawCerts [][]byte, chains [][]*x509.Certificate) error {
+			if verify != nil {
+				if err := verify(rawCerts, chains); err != nil {
+					return err
+				}
+			}
+			return verifyPinnedCert(rawCerts, pins)
+		}
+	}
+	return config
+}
+
 // Authenticate
 func (cn *connection) authenticate(username, password string) (*graph.AuthResponse, error) {
 	resp, err := cn.graph.Authenticate([]byte(username), []byte(password))
+	return cn.checkAuthResponse(resp, err)
+}
+
+// Convert the failed authentication into an AuthError, the transport is closed if the request failed
+func (cn *connection) checkAuthResponse(resp *graph.AuthResponse, err error) (*graph.AuthResponse, error) {
 	if err != nil {
-		err = fmt.Errorf("authentication fails, %s", err.Error())
+		authErr := &AuthError{
+			Msg:  fmt.Sprintf("authentication fails, %s", err.Error()),
+			Code: ErrorCode_E_RPC_FAILURE,
+			Err:  err,
+		}
 		if e := cn.graph.Close(); e != nil {
-			err = fmt.Errorf("fail to close transport, error: %s", e.Error())
+			return nil, &TransportError{Msg: fmt.Sprintf("fail to close transport, error: %s", e.Error()), Err: authErr}
 		}
-		return nil, err
+		return nil, authErr
 	}
 	if resp.ErrorCode != nebula.ErrorCode_SUCCEEDED {
-		return nil, fmt.Errorf("fail to authenticate, error: %s", resp.ErrorMsg)
+		return nil, &AuthError{
+			Msg:       fmt.Sprintf("fail to authenticate, error: %s", resp.ErrorMsg),
+			Code:      ErrorCode(resp.ErrorCode),
+			ServerMsg: string(resp.ErrorMsg),
+		}
 	}
 	return resp, err
 }
 
 func (cn *connection) execute(sessionID int64, stmt string) (*graph.ExecutionResponse, error) {
-	return cn.graph.Execute(sessionID, []byte(stmt))
+	resp, err := cn.graph.Execute(sessionID, []byte(stmt))
+	if err != nil {
+		return nil, cn.checkResponseSize(err)
+	}
+	return resp, nil
+}
+
+// Reopen the transport if the request failed with a response exceeding the max result size,
+// the rest of the response is left in the transport and would be read by the next request
+func (cn *connection) checkResponseSize(err error) error {
+	if cn.maxResult <= 0 || !isFrameTooLarge(err) {
+		return err
+	}
+	if _err := cn.reopen(); _err != nil {
+		return fmt.Errorf("failed to reopen connection after an oversized response, error: %s", _err.Error())
+	}
+	return fmt.Errorf("failed to execute: the response exceeds the max result size of %d bytes", cn.maxResult)
+}
+
+// Check if the error is raised by the framed transport for a frame exceeding its max length
+func isFrameTooLarge(err error) bool {
+	transErr, ok := err.(thrift.TransportException)
+	return ok && transErr.TypeID() == thrift.UNKNOWN_TRANSPORT_EXCEPTION &&
+		strings.HasPrefix(transErr.Error(), "Incorrect frame size")
+}
+
+// executeWithContext aborts the request when ctx is cancelled or its deadline expires, see callWithContext
+func (cn *connection) executeWithContext(ctx context.Context, sessionID int64, stmt string) (*graph.ExecutionResponse, error) {
+	var resp *graph.ExecutionResponse
+	err := cn.callWithContext(ctx, "execute", func() (err error) {
+		resp, err = cn.execute(sessionID, stmt)
+		return err
+	})
+	return resp, err
+}
+
+// authenticateWithContext aborts the authentication when ctx is cancelled or its deadline expires,
+// see callWithContext
+func (cn *connection) authenticateWithContext(ctx context.Context, username, password string) (*graph.AuthResponse, error) {
+	var resp *graph.AuthResponse
+	err := cn.callWithContext(ctx, "authenticate", func() (err error) {
+		// The transport is not closed while the request may be interrupted
+		resp, err = cn.graph.Authenticate([]byte(username), []byte(password))
+		return err
+	})
+	if err != nil && ctx.Err() != nil {
+		// The transport is reopened after the abort
+		return nil, err
+	}
+	return cn.checkAuthResponse(resp, err)
 }
 
-// unsupported
-// func (client *GraphClient) ExecuteJson((sessionID int64, stmt string) (*graph.ExecutionResponse, error) {
-// 	return cn.graph.ExecuteJson(sessionID, []byte(stmt))
-// }
+// callWithContext makes the request and aborts it when ctx is cancelled or its deadline expires,
+// in which case the returned error wraps ctx.Err(). The transport is reopened after an abort,
+// otherwise the response of the aborted request would be read by the next request.
+func (cn *connection) callWithContext(ctx context.Context, action string, call func() error) error {
+	if ctx.Done() == nil {
+		return call()
+	}
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to %s: %w", action, err)
+	}
+	if cn.sock == nil {
+		// A client created by newService has no transport to interrupt
+		return call()
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		cn.sock.SetTimeout(time.Until(deadline))
+		defer cn.sock.SetTimeout(cn.timeout)
+	}
+
+	done := make(chan struct{})
+	interrupted := make(chan bool, 1)
+	go func() {
+		select {
+		case <-ctx.Done():
+			cn.sock.Interrupt()
+			interrupted <- true
+		case <-done:
+			interrupted <- false
+		}
+	}()
+	err := call()
+	close(done)
+	if deadline, ok := ctx.Deadline(); ok && err != nil && !time.Now().Before(deadline) {
+		// The socket timeout may fire just before the deadline of ctx
+		<-ctx.Done()
+	}
+
+	if <-interrupted || (err != nil && ctx.Err() != nil) {
+		if _err := cn.reopen(); _err != nil {
+			return fmt.Errorf("failed to reopen connection after the request was aborted, error: %s", _err.Error())
+		}
+		if err != nil {
+			return fmt.Errorf("failed to %s: %w", action, ctx.Err())
+		}
+	}
+	return err
+}
+
+func (cn *connection) executeJson(sessionID int64, stmt string) ([]byte, error) {
+	resp, err := cn.graph.ExecuteJson(sessionID, []byte(stmt))
+	if err != nil {
+		return nil, cn.checkResponseSize(err)
+	}
+	return resp, nil
+}
+
+// executeJsonWithContext aborts the request when ctx is cancelled or its deadline expires, see callWithContext
+func (cn *connection) executeJsonWithContext(ctx context.Context, sessionID int64, stmt string) ([]byte, error) {
+	var resp []byte
+	err := cn.callWithContext(ctx, "execute", func() (err error) {
+		resp, err = cn.executeJson(sessionID, stmt)
+		return err
+	})
+	return resp, err
+}
 
 // Check connection to host address
 func (cn *connection) ping() bool {
-	_, err := cn.execute(0, "YIELD 1")
-	return err == nil
+	return cn.verify() == nil
+}
+
+// Check connection to host address and return the error if the host is unreachable
+func (cn *connection) verify() error {
+	_, err := cn.execute(0, cn.healthCheckStmt())
+	return err
+}
+
+func (cn *connection) healthCheckStmt() string {
+	if cn.checkStmt == "" {
+		return defaultHealthCheckStmt
+	}
+	return cn.checkStmt
+}
+
+// Return the net.Conn of the transport, an error is returned if the connection has none
+func (cn *connection) netConn() (net.Conn, error) {
+	if cn.sock == nil {
+		return nil, fmt.Errorf("the connection to host %s is not backed by a socket", cn.severAddress.String())
+	}
+	conn := cn.sock.Conn()
+	if conn == nil {
+		return nil, fmt.Errorf("the connection to host %s is closed", cn.severAddress.String())
+	}
+	return conn, nil
 }
 
 // Sign out and release seesin ID
@@ -93,6 +430,12 @@ func (cn *connection) signOut(sessionID int64) error {
 	return cn.graph.Signout(sessionID)
 }
 
+// Close the transport and open it again with the same address and timeout
+func (cn *connection) reopen() error {
+	cn.close()
+	return cn.open(cn.severAddress, cn.timeout)
+}
+
 // Update returnedAt for cleaner
 func (cn *connection) release() {
 	cn.returnedAt = time.Now()
@@ -8,26 +8,93 @@ package nebula_go
 
 import (
 	"container/list"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"errors"
 	"fmt"
+	"net"
 	"sync"
 	"time"
 
 	"github.com/vesoft-inc/nebula-go/v2/nebula"
 )
 
+// errPoolExhausted is returned when there is no idle connection and no new connection can be created
+var errPoolExhausted = errors.New("failed to get connection: No valid connection" +
+	" in the idle queue and connection number has reached the pool capacity")
+
+// hostState tracks the connection failures of a host
+type hostState struct {
+	failures    int       // number of consecutive failures to connect to the host
+	lastFailure time.Time // time of the last failure
+	retryAt     time.Time // the host is skipped when creating new connections until retryAt
+	// The consecutive failures counted by the circuit breaker since windowStart
+	windowFailures int
+	windowStart    time.Time
+	breakerOpen    bool      // the breaker is half-open once openUntil is passed
+	openUntil      time.Time // the host is skipped entirely until openUntil
+}
+
+// BreakerState is the state of the circuit breaker of a host, see CircuitBreaker
+type BreakerState string
+
+const (
+	// The host is used
+	BreakerClosed BreakerState = "closed"
+	// The host is skipped entirely
+	BreakerOpen BreakerState = "open"
+	// A single connection attempt to the host is allowed as a trial
+	BreakerHalfOpen BreakerState = "half-open"
+)
+
+// Return the state of the circuit breaker of the host
+func (state *hostState) breakerState(now time.Time) BreakerState {
+	switch {
+	case !state.breakerOpen:
+		return BreakerClosed
+	case now.Before(state.openUntil):
+		return BreakerOpen
+	default:
+		return BreakerHalfOpen
+	}
+}
+
 type ConnectionPool struct {
 	idleConnectionQueue   list.List
 	activeConnectionQueue list.List
 	addresses             []HostAddress
+	staticAddresses       []HostAddress            // the resolved addresses given to the pool, the others are discovered by DNSHost
+	hostLabels            map[HostAddress][]string // labels of the resolved addresses
 	conf                  PoolConfig
-	hostIndex             int
-	log                   Logger
+	sslConfig             *tls.Config
+	hostStates            map[HostAddress]*hostState
+	serverNames           map[HostAddress]string // ssl server names of the resolved addresses
+	certPins              [][sha256.Size]byte    // fingerprints of the pinned certificates
+	log                   StructuredLogger
 	rwLock                sync.RWMutex
+	waitCount             int64         // number of acquisitions which found no idle connection
+	waitDuration          time.Duration // total time spent by these acquisitions
 	cleanerChan           chan struct{} //notify when pool is close
+	keepAliveChan         chan struct{} //notify when pool is close
+	drainedChan           chan struct{} //notify when all active connections are returned during shutdown
+	waiters               list.List     // chan struct{} of the acquisitions waiting for a released connection
+	resultSlots           chan struct{} // a slot of MaxResultBytes is taken by each query executed, nil if unlimited
+	discoveryChan         chan struct{} //notify when pool is close
+	lookupIP              func(host string) ([]net.IP, error)
+	draining              bool
 	closed                bool
 }
 
 func NewConnectionPool(addresses []HostAddress, conf PoolConfig, log Logger) (*ConnectionPool, error) {
+	return NewSslConnectionPool(addresses, conf, nil, log)
+}
+
+// NewSslConnectionPool creates a connection pool whose connections are established with ssl.
+// The config is used for every new connection, so a GetClientCertificate callback set in it
+// is invoked on each handshake and can supply a rotated client certificate.
+// The events are logged to log unless PoolConfig.Logger is set, nil log means they are discarded.
+func NewSslConnectionPool(addresses []HostAddress, conf PoolConfig, sslConfig *tls.Config, log Logger) (*ConnectionPool, error) {
 	// Process domain to IP
 	convAddress, err := DomainToIP(addresses)
 	if err != nil {
@@ -35,33 +102,83 @@ func NewConnectionPool(addresses []HostAddress, conf PoolConfig, log Logger) (*C
 	}
 
 	// Check input
-	if len(convAddress) == 0 {
+	if len(convAddress) == 0 && conf.DNSHost.Host == "" {
 		return nil, fmt.Errorf("failed to initialize connection pool: illegal address input")
 	}
 
 	// Check config
-	conf.validateConf(log)
+	if err = conf.Validate(); err != nil {
+		return nil, fmt.Errorf("failed to initialize connection pool: %w", err)
+	}
+	if len(conf.PinnedCertSHA256) > 0 && sslConfig == nil {
+		return nil, fmt.Errorf("failed to initialize connection pool: PinnedCertSHA256 requires an ssl config")
+	}
 
 	newPool := &ConnectionPool{
-		conf:      conf,
-		log:       log,
-		addresses: convAddress,
-		hostIndex: 0,
+		conf:            conf,
+		sslConfig:       sslConfig,
+		log:             newPoolLogger(conf, log),
+		addresses:       convAddress,
+		staticAddresses: convAddress,
+		hostStates:      make(map[HostAddress]*hostState),
+		lookupIP:        net.LookupIP,
+	}
+	if conf.MaxTotalResultBytes > 0 {
+		newPool.resultSlots = make(chan struct{}, conf.MaxTotalResultBytes/conf.MaxResultBytes)
+	}
+	if len(conf.SSLServerNames) > 0 {
+		newPool.serverNames = make(map[HostAddress]string)
+		for i, addr := range addresses {
+			if name, ok := conf.SSLServerNames[addr.String()]; ok {
+				newPool.serverNames[convAddress[i]] = name
+			}
+		}
+	}
+	if len(conf.HostLabels) > 0 {
+		newPool.hostLabels = make(map[HostAddress][]string)
+		for i, addr := range addresses {
+			if labels, ok := conf.HostLabels[addr.String()]; ok {
+				newPool.hostLabels[convAddress[i]] = labels
+			}
+		}
+	}
+	for _, pin := range conf.PinnedCertSHA256 {
+		// The pins are checked by Validate
+		fingerprint, _ := parseCertPin(pin)
+		newPool.certPins = append(newPool.certPins, fingerprint)
+	}
+	if conf.DNSHost.Host != "" {
+		discovered, err := lookupHostIPs(newPool.lookupIP, conf.DNSHost)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize connection pool: %w", err)
+		}
+		newPool.setDiscoveredHosts(discovered)
+	}
+	if conf.MaxConnsPerHost > 0 && conf.MinConnPoolSize > conf.MaxConnsPerHost*len(newPool.addresses) {
+		return nil, fmt.Errorf("failed to initialize connection pool: MinConnPoolSize %d exceeds MaxConnsPerHost %d "+
+			"for %d addresses", conf.MinConnPoolSize, conf.MaxConnsPerHost, len(newPool.addresses))
 	}
 	if err = newPool.initPool(); err != nil {
 		return nil, err
 	}
 	newPool.startCleaner()
+	newPool.startKeepAlive()
+	newPool.startDiscovery()
 	return newPool, nil
 }
 
 func (pool *ConnectionPool) initPool() error {
 	for i := 0; i < pool.conf.MinConnPoolSize; i++ {
 		// Simple round-robin
-		newConn := newConnection(pool.addresses[i%len(pool.addresses)])
+		newConn := pool.buildConnection(pool.addresses[i%len(pool.addresses)])
 
 		// Open connection to host
 		err := newConn.open(newConn.severAddress, pool.conf.TimeOut)
+		if err != nil && pool.conf.WarmupBestEffort {
+			pool.log.Warn("failed to open connection during warmup", "host", newConn.severAddress, "error", err)
+			pool.markHostFailed(newConn.severAddress)
+			continue
+		}
 		if err != nil {
 			// If initialization failed, clean idle queue
 			idleLen := pool.idleConnectionQueue.Len()
@@ -74,17 +191,122 @@ func (pool *ConnectionPool) initPool() error {
 		// Mark connection as in use
 		pool.idleConnectionQueue.PushBack(newConn)
 	}
-	pool.log.Info("connection pool is initialized successfully")
+	if idleLen := pool.idleConnectionQueue.Len(); idleLen < pool.conf.MinConnPoolSize {
+		pool.log.Warn("connections are missing after warmup", "opened", idleLen, "min_size", pool.conf.MinConnPoolSize)
+	}
+	pool.log.Info("connection pool is initialized successfully", "hosts", len(pool.addresses),
+		"connections", pool.idleConnectionQueue.Len())
 	return nil
 }
 
+// CredentialProvider returns the username and password to authenticate a session with,
+// e.g. a short-lived token issued by an external auth service as the password
+type CredentialProvider func() (username, password string, err error)
+
+// Return a provider of the given credentials
+func staticCredentials(username, password string) CredentialProvider {
+	return func() (string, string, error) {
+		return username, password, nil
+	}
+}
+
 func (pool *ConnectionPool) GetSession(username, password string) (*Session, error) {
-	// Get valid and usable connection
+	return pool.GetSessionWithCredentials(staticCredentials(username, password))
+}
+
+// GetSessionWithCredentials creates a session authenticated with the credentials returned by provider.
+// If ReauthOnSessionExpired is set in the pool config, the session keeps the provider and calls it
+// again to re-authenticate, so refreshed credentials are used.
+func (pool *ConnectionPool) GetSessionWithCredentials(provider CredentialProvider) (*Session, error) {
+	conn, err := pool.acquireConn()
+	if err != nil {
+		return nil, err
+	}
+	return pool.newSession(context.Background(), conn, provider)
+}
+
+// GetSessionWithLabel creates a session as GetSession on a connection to a host carrying the label,
+// see HostLabels in the pool config. The session is only reconnected or failed over to hosts
+// carrying the label.
+func (pool *ConnectionPool) GetSessionWithLabel(label, username, password string) (*Session, error) {
+	exclude, err := pool.hostsWithoutLabel(label)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := pool.acquireConn(exclude...)
+	if err != nil {
+		return nil, err
+	}
+	session, err := pool.newSession(context.Background(), conn, staticCredentials(username, password))
+	if err != nil {
+		return nil, err
+	}
+	session.label = label
+	return session, nil
+}
+
+// GetSessionWithAffinity creates a session as GetSession on a connection to the host selected by the
+// AffinityHasher of the pool config for the key, so the sessions of the same key consistently execute
+// their queries on the same host, e.g. to hit the caches of the host for repeated query patterns.
+// The key is rehashed to the next available host if its host fails to be connected, is in backoff,
+// has an open circuit breaker or reached MaxConnsPerHost. A session reconnected after a connection failure is not kept
+// on the host of its key.
+func (pool *ConnectionPool) GetSessionWithAffinity(key, username, password string) (*Session, error) {
+	var conn *connection
+	var err error
+	var failed []HostAddress
+	const retryTimes = 3
+	start := time.Now()
+	for i := 0; i < retryTimes; i++ {
+		var host HostAddress
+		var others []HostAddress
+		if host, others, err = pool.affinityHost(key, failed...); err != nil {
+			return nil, err
+		}
+		if conn, err = pool.getIdleConn(others...); err == nil {
+			break
+		}
+		// The key is rehashed to the next available host on the next attempt
+		if err != errPoolExhausted {
+			failed = append(failed, host)
+		}
+	}
+	if conn == nil {
+		return nil, err
+	}
+	pool.connAcquired(conn, start)
+	return pool.newSession(context.Background(), conn, staticCredentials(username, password))
+}
+
+// Return the host selected for the affinity key among the hosts which are not excluded, and the other hosts
+func (pool *ConnectionPool) affinityHost(key string, exclude ...HostAddress) (HostAddress, []HostAddress, error) {
+	pool.rwLock.Lock()
+	defer pool.rwLock.Unlock()
+	candidates, err := pool.hostCandidates(exclude...)
+	if err != nil {
+		return HostAddress{}, nil, err
+	}
+	i := pool.conf.AffinityHasher.Select(key, candidates)
+	if i < 0 || i >= len(candidates) {
+		return HostAddress{}, nil, fmt.Errorf("failed to get connection: affinity hasher selected invalid host index %d", i)
+	}
+	var others []HostAddress
+	for _, host := range pool.addresses {
+		if host != candidates[i].Address {
+			others = append(others, host)
+		}
+	}
+	return candidates[i].Address, others, nil
+}
+
+// Get a valid connection, retrying as the idle connections may be closed meanwhile
+func (pool *ConnectionPool) acquireConn(exclude ...HostAddress) (*connection, error) {
 	var conn *connection = nil
 	var err error = nil
 	const retryTimes = 3
+	start := time.Now()
 	for i := 0; i < retryTimes; i++ {
-		conn, err = pool.getIdleConn()
+		conn, err = pool.getIdleConn(exclude...)
 		if err == nil {
 			break
 		}
@@ -92,14 +314,79 @@ func (pool *ConnectionPool) GetSession(username, password string) (*Session, err
 	if conn == nil {
 		return nil, err
 	}
-	// Authenticate
-	resp, err := conn.authenticate(username, password)
+	pool.connAcquired(conn, start)
+	return conn, nil
+}
+
+// Return the hosts not carrying the label, nil if the label is empty.
+// An error is returned if no host carries the label.
+func (pool *ConnectionPool) hostsWithoutLabel(label string) ([]HostAddress, error) {
+	if label == "" {
+		return nil, nil
+	}
+	// The addresses are replaced by the host discovery
+	pool.rwLock.RLock()
+	defer pool.rwLock.RUnlock()
+	var without []HostAddress
+	for _, host := range pool.addresses {
+		if !containsLabel(pool.hostLabels[host], label) {
+			without = append(without, host)
+		}
+	}
+	if len(without) == len(pool.addresses) {
+		return nil, fmt.Errorf("failed to get connection: no host carries the label %s", label)
+	}
+	return without, nil
+}
+
+func containsLabel(labels []string, label string) bool {
+	for _, l := range labels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
+
+// GetSessionContext creates a session as GetSession, but waits for a connection to be released
+// if the pool has reached its capacity instead of failing. Opening a new connection and the
+// authentication are aborted when ctx is done, in which case the returned error wraps ctx.Err().
+func (pool *ConnectionPool) GetSessionContext(ctx context.Context, username, password string) (*Session, error) {
+	start := time.Now()
+	conn, err := pool.waitIdleConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pool.connAcquired(conn, start)
+	return pool.newSession(ctx, conn, staticCredentials(username, password))
+}
+
+// Authenticate on the connection and create a session holding it, the authentication is aborted when ctx is done.
+// The session uses the SpaceName of the pool config.
+func (pool *ConnectionPool) newSession(ctx context.Context, conn *connection, credentials CredentialProvider) (*Session, error) {
+	session, err := pool.authenticateSession(ctx, conn, credentials)
+	if err != nil {
+		return nil, err
+	}
+	if err := session.useConfiguredSpace(); err != nil {
+		session.Release()
+		return nil, err
+	}
+	return session, nil
+}
+
+// Authenticate on the connection and create a session holding it as newSession, without using a space
+func (pool *ConnectionPool) authenticateSession(ctx context.Context, conn *connection,
+	credentials CredentialProvider) (*Session, error) {
+	username, password, err := credentials()
+	if err != nil {
+		pool.putBack(conn)
+		return nil, fmt.Errorf("failed to get credentials, error: %w", err)
+	}
+	resp, err := conn.authenticateWithContext(ctx, username, password)
 	if err != nil || resp.GetErrorCode() != nebula.ErrorCode_SUCCEEDED {
 		// if authentication failed, put connection back
-		pool.rwLock.Lock()
-		defer pool.rwLock.Unlock()
-		removeFromList(&pool.activeConnectionQueue, conn)
-		pool.idleConnectionQueue.PushBack(conn)
+		pool.putBack(conn)
 		return nil, err
 	}
 
@@ -114,28 +401,157 @@ func (pool *ConnectionPool) GetSession(username, password string) (*Session, err
 		log:          pool.log,
 		timezoneInfo: timezoneInfo{timezoneOffset, timezoneName},
 	}
+	if pool.conf.ReauthOnSessionExpired {
+		newSession.credentials = credentials
+	}
 
 	return &newSession, nil
 }
 
-func (pool *ConnectionPool) getIdleConn() (*connection, error) {
+// Put back a connection which is not used by a session
+func (pool *ConnectionPool) putBack(conn *connection) {
+	pool.rwLock.Lock()
+	defer pool.rwLock.Unlock()
+	removeFromList(&pool.activeConnectionQueue, conn)
+	pool.idleConnectionQueue.PushBack(conn)
+	pool.notifyWaiter()
+}
+
+// WithSession creates a session, runs fn with it and releases the session afterwards.
+// The error of fn is returned. The session is released even if fn panics,
+// in which case the panic is propagated to the caller after the release.
+func (pool *ConnectionPool) WithSession(username, password string, fn func(*Session) error) error {
+	session, err := pool.GetSession(username, password)
+	if err != nil {
+		return err
+	}
+	defer session.Release()
+	return fn(session)
+}
+
+// CheckSpace checks that the SpaceName of the pool config exists, e.g. right after the pool is created
+// to catch a typo in the config at startup rather than when the first session is created.
+// It costs a session authenticated with the given credentials to list the spaces, so it is left
+// to the caller instead of being done by NewConnectionPool.
+func (pool *ConnectionPool) CheckSpace(username, password string) error {
+	space := pool.conf.SpaceName
+	if space == "" {
+		return fmt.Errorf("failed to check space: SpaceName is not set in the pool config")
+	}
+	conn, err := pool.acquireConn()
+	if err != nil {
+		return fmt.Errorf("failed to check space %s, %w", space, err)
+	}
+	session, err := pool.authenticateSession(context.Background(), conn, staticCredentials(username, password))
+	if err != nil {
+		return fmt.Errorf("failed to check space %s, %w", space, err)
+	}
+	defer session.Release()
+	spaces, err := session.ShowSpaces()
+	if err != nil {
+		return fmt.Errorf("failed to check space %s, %w", space, err)
+	}
+	for _, name := range spaces {
+		if name == space {
+			return nil
+		}
+	}
+	return fmt.Errorf("failed to check space: the space %s of the pool config does not exist", space)
+}
+
+// Get a valid connection, the connections to the excluded hosts are skipped
+func (pool *ConnectionPool) getIdleConn(exclude ...HostAddress) (*connection, error) {
 	pool.rwLock.Lock()
 	defer pool.rwLock.Unlock()
+	return pool.getIdleConnLocked(context.Background(), exclude...)
+}
+
+// Get a valid connection as getIdleConn, waiting for a connection to be released if the pool
+// has reached its capacity until ctx is done
+func (pool *ConnectionPool) waitIdleConn(ctx context.Context) (*connection, error) {
+	for {
+		pool.rwLock.Lock()
+		conn, err := pool.getIdleConnLocked(ctx)
+		if err != errPoolExhausted {
+			pool.rwLock.Unlock()
+			return conn, err
+		}
+		notify := make(chan struct{}, 1)
+		waiter := pool.waiters.PushBack(notify)
+		pool.rwLock.Unlock()
+
+		select {
+		case <-notify:
+		case <-ctx.Done():
+			pool.rwLock.Lock()
+			pool.waiters.Remove(waiter)
+			select {
+			case <-notify:
+				// Pass the released connection on to the next waiter
+				pool.notifyWaiter()
+			default:
+			}
+			pool.rwLock.Unlock()
+			return nil, fmt.Errorf("failed to get connection: %w", ctx.Err())
+		}
+	}
+}
+
+// Wake up the first waiter for a released connection, the caller should hold the lock
+func (pool *ConnectionPool) notifyWaiter() {
+	if front := pool.waiters.Front(); front != nil {
+		pool.waiters.Remove(front)
+		front.Value.(chan struct{}) <- struct{}{}
+	}
+}
+
+// Get a valid connection as getIdleConn, a new connection must be opened before ctx is done.
+// The caller should hold the lock
+func (pool *ConnectionPool) getIdleConnLocked(ctx context.Context, exclude ...HostAddress) (*connection, error) {
+	start := time.Now()
+	if pool.closed || pool.draining {
+		return nil, fmt.Errorf("failed to get connection: the pool is shut down")
+	}
 
 	// Take an idle valid connection if possible
 	if pool.idleConnectionQueue.Len() > 0 {
 		var newConn *connection = nil
 		var newEle *list.Element = nil
-		for ele := pool.idleConnectionQueue.Front(); ele != nil; ele = ele.Next() {
-			// Check if connection is valid
-			if res := ele.Value.(*connection).ping(); res {
-				newConn = ele.Value.(*connection)
-				newEle = ele
-				break
+		checkBefore := start.Add(-pool.conf.MaxConnIdleBeforeCheck)
+		var expired []*list.Element
+		// The connections are returned to the back of the queue
+		first, next := pool.idleConnectionQueue.Front(), (*list.Element).Next
+		if pool.conf.IdleOrder == IdleLIFO {
+			first, next = pool.idleConnectionQueue.Back(), (*list.Element).Prev
+		}
+		for ele := first; ele != nil; ele = next(ele) {
+			conn := ele.Value.(*connection)
+			if pool.isExpired(conn, start) {
+				expired = append(expired, ele)
+				continue
+			}
+			if containsHost(exclude, conn.severAddress) || pool.breakerState(conn.severAddress, start) == BreakerOpen {
+				continue
 			}
+			// Check if connection is valid, a half-open connection is reopened
+			if !conn.returnedAt.After(checkBefore) && !conn.ping() {
+				if err := conn.reopen(); err != nil {
+					pool.log.Warn("failed to reopen idle connection", "host", conn.severAddress, "error", err)
+					continue
+				}
+				pool.log.Debug("reopened idle connection after a failed ping", "host", conn.severAddress)
+			}
+			newConn = conn
+			newEle = ele
+			break
+		}
+		for _, ele := range expired {
+			pool.idleConnectionQueue.Remove(ele)
+			ele.Value.(*connection).close()
 		}
 		if newConn == nil {
-			return pool.createConnection()
+			defer pool.recordWait(start)
+			return pool.createConnection(ctx, exclude...)
 		}
 		// Remove new connection from idle and add to active if found
 		pool.idleConnectionQueue.Remove(newEle)
@@ -144,7 +560,8 @@ func (pool *ConnectionPool) getIdleConn() (*connection, error) {
 	}
 
 	// Create a new connection if there is no idle connection and total connection < pool max size
-	newConn, err := pool.createConnection()
+	defer pool.recordWait(start)
+	newConn, err := pool.createConnection(ctx, exclude...)
 	// TODO: If no idle avaliable, wait for timeout and reconnect
 	return newConn, err
 }
@@ -152,16 +569,90 @@ func (pool *ConnectionPool) getIdleConn() (*connection, error) {
 // Release connection to pool
 func (pool *ConnectionPool) release(conn *connection) {
 	pool.rwLock.Lock()
-	defer pool.rwLock.Unlock()
-	// Remove connection from active queue and add into idle queue
+	// Remove connection from active queue and add into idle queue, unless it expired
 	removeFromList(&pool.activeConnectionQueue, conn)
-	conn.release()
-	pool.idleConnectionQueue.PushBack(conn)
+	expired := pool.isExpired(conn, time.Now())
+	replace := false
+	// The connections to the hosts removed by the discovery are drained
+	if expired || (pool.conf.DNSHost.Host != "" && !containsHost(pool.addresses, conn.severAddress)) {
+		conn.close()
+		// An expired connection is replaced to keep the min pool size
+		replace = expired && !pool.closed && !pool.draining &&
+			pool.idleConnectionQueue.Len()+pool.activeConnectionQueue.Len() < pool.conf.MinConnPoolSize
+	} else {
+		conn.release()
+		pool.idleConnectionQueue.PushBack(conn)
+	}
+	// Either the connection or its slot can be taken by a waiter
+	pool.notifyWaiter()
+	if pool.drainedChan != nil && pool.activeConnectionQueue.Len() == 0 {
+		close(pool.drainedChan)
+		pool.drainedChan = nil
+	}
+	pool.rwLock.Unlock()
+	if replace {
+		pool.replaceConn()
+	}
+}
+
+// Open a connection replacing an expired one and add it to the idle queue. The connection is opened
+// out of the lock, and closed if the pool was closed or reached its max size meanwhile.
+func (pool *ConnectionPool) replaceConn() {
+	pool.rwLock.Lock()
+	host, err := pool.getHost()
+	if err != nil {
+		pool.rwLock.Unlock()
+		pool.log.Warn("failed to replace expired connection", "error", err)
+		return
+	}
+	newConn := pool.buildConnection(host)
+	pool.rwLock.Unlock()
+
+	err = newConn.open(host, pool.conf.TimeOut)
+	pool.rwLock.Lock()
+	defer pool.rwLock.Unlock()
+	if err != nil {
+		pool.markHostFailed(host)
+		pool.log.Warn("failed to replace expired connection", "host", host, "error", err)
+		return
+	}
+	pool.markHostAvailable(host)
+	if pool.closed || pool.draining ||
+		pool.idleConnectionQueue.Len()+pool.activeConnectionQueue.Len() >= pool.conf.MaxConnPoolSize {
+		newConn.close()
+		return
+	}
+	pool.log.Debug("opened connection", "host", host)
+	pool.idleConnectionQueue.PushBack(newConn)
+	pool.notifyWaiter()
+}
+
+// Check if the connection reached the max lifetime
+func (pool *ConnectionPool) isExpired(conn *connection, now time.Time) bool {
+	return pool.conf.MaxConnLifetime > 0 && !conn.openedAt.IsZero() &&
+		now.Sub(conn.openedAt) >= pool.conf.MaxConnLifetime
+}
+
+// UpdateTLSConfig replaces the ssl config used for new connections of the pool.
+// Open connections keep their config until they are closed.
+func (pool *ConnectionPool) UpdateTLSConfig(sslConfig *tls.Config) error {
+	if sslConfig == nil {
+		return fmt.Errorf("failed to update TLS config: the config is nil")
+	}
+	pool.rwLock.Lock()
+	defer pool.rwLock.Unlock()
+	if pool.sslConfig == nil {
+		return fmt.Errorf("failed to update TLS config: the pool is not created with ssl")
+	}
+	pool.sslConfig = sslConfig
+	return nil
 }
 
 // Check avaliability of host
 func (pool *ConnectionPool) Ping(host HostAddress, timeout time.Duration) error {
-	newConn := newConnection(host)
+	pool.rwLock.RLock()
+	newConn := pool.buildConnection(host)
+	pool.rwLock.RUnlock()
 	// Open connection to host
 	if err := newConn.open(newConn.severAddress, timeout); err != nil {
 		return err
@@ -170,10 +661,76 @@ func (pool *ConnectionPool) Ping(host HostAddress, timeout time.Duration) error
 	return nil
 }
 
+// PingHost checks the availability of the given host using the timeout of the pool.
+// It distinguishes a host that could not be connected at all from a host that
+// accepted the connection but failed to respond to the check query.
+func (pool *ConnectionPool) PingHost(host HostAddress) error {
+	pool.rwLock.RLock()
+	newConn := pool.buildConnection(host)
+	pool.rwLock.RUnlock()
+	// Open connection to host
+	if err := newConn.open(newConn.severAddress, pool.conf.TimeOut); err != nil {
+		return fmt.Errorf("failed to connect to host %s, error: %s", host.String(), err.Error())
+	}
+	defer newConn.close()
+	if err := newConn.verify(); err != nil {
+		return fmt.Errorf("connected to host %s but failed to execute the check query, error: %s",
+			host.String(), err.Error())
+	}
+	return nil
+}
+
 // Close all connection
 func (pool *ConnectionPool) Close() {
+	pool.closeAll()
+}
+
+// Shutdown stops handing out connections and waits for the active connections to be returned
+// before closing all connections. If ctx is done before, the remaining active connections are
+// closed anyway and an error holding their number and wrapping ctx.Err() is returned.
+func (pool *ConnectionPool) Shutdown(ctx context.Context) error {
+	pool.rwLock.Lock()
+	if pool.closed {
+		pool.rwLock.Unlock()
+		return nil
+	}
+	pool.draining = true
+	// Wake up the waiters to fail as the pool is shut down
+	for pool.waiters.Len() > 0 {
+		pool.notifyWaiter()
+	}
+	pool.log.Info("shutting down connection pool", "active_connections", pool.activeConnectionQueue.Len())
+	drained := pool.drainedChan
+	if drained == nil {
+		drained = make(chan struct{})
+		if pool.activeConnectionQueue.Len() == 0 {
+			close(drained)
+		} else {
+			pool.drainedChan = drained
+		}
+	}
+	pool.rwLock.Unlock()
+
+	select {
+	case <-drained:
+		pool.closeAll()
+		return nil
+	case <-ctx.Done():
+		if terminated := pool.closeAll(); terminated > 0 {
+			return fmt.Errorf("failed to shut down the pool gracefully: %d active connections are terminated, error: %w",
+				terminated, ctx.Err())
+		}
+		return nil
+	}
+}
+
+// Close all connections and return the number of active connections closed
+func (pool *ConnectionPool) closeAll() int {
 	pool.rwLock.Lock()
 	defer pool.rwLock.Unlock()
+	if pool.closed {
+		return 0
+	}
 	idleLen := pool.idleConnectionQueue.Len()
 	activeLen := pool.activeConnectionQueue.Len()
 
@@ -187,9 +744,138 @@ func (pool *ConnectionPool) Close() {
 	}
 
 	pool.closed = true
+	for pool.waiters.Len() > 0 {
+		pool.notifyWaiter()
+	}
 	if pool.cleanerChan != nil {
 		close(pool.cleanerChan)
 	}
+	if pool.keepAliveChan != nil {
+		close(pool.keepAliveChan)
+	}
+	if pool.discoveryChan != nil {
+		close(pool.discoveryChan)
+	}
+	return activeLen
+}
+
+// Reserve MaxResultBytes for the response of a query if MaxTotalResultBytes is set, waiting for a reservation
+// to be released until ctx is done. The returned func releases the reservation.
+func (pool *ConnectionPool) reserveResult(ctx context.Context) (func(), error) {
+	if pool.resultSlots == nil {
+		return func() {}, nil
+	}
+	select {
+	case pool.resultSlots <- struct{}{}:
+		return func() { <-pool.resultSlots }, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("failed to execute: waiting for the responses of other queries to fit in MaxTotalResultBytes: %w",
+			ctx.Err())
+	}
+}
+
+// Report the connection acquired after waiting since start to the hooks and the logger
+func (pool *ConnectionPool) connAcquired(conn *connection, start time.Time) {
+	waited := time.Since(start)
+	pool.log.Debug("acquired connection", "host", conn.severAddress, "waited", waited)
+	pool.conf.Hooks.connAcquire(waited)
+}
+
+// Record an acquisition which found no idle connection
+func (pool *ConnectionPool) recordWait(start time.Time) {
+	pool.waitCount++
+	pool.waitDuration += time.Since(start)
+}
+
+// PoolStats is a snapshot of the statistics of the connection pool
+type PoolStats struct {
+	TotalConns  int `json:"total_conns"`
+	IdleConns   int `json:"idle_conns"`
+	ActiveConns int `json:"active_conns"`
+	// Number of connections per host, keyed by host:port
+	HostConns map[string]int `json:"host_conns"`
+	// Number of connection acquisitions which found no idle connection in the pool
+	// and the total time spent by these acquisitions
+	WaitCount    int64         `json:"wait_count"`
+	WaitDuration time.Duration `json:"wait_duration"`
+	// State of the circuit breaker per host, keyed by host:port
+	HostBreakers map[string]BreakerState `json:"host_breakers"`
+	// Total size reserved by the queries being executed for their responses, 0 if MaxTotalResultBytes
+	// is not set, see PoolConfig.MaxTotalResultBytes
+	ResultBytesInUse int64 `json:"result_bytes_in_use"`
+}
+
+// Stats returns a snapshot of the statistics of the connection pool
+func (pool *ConnectionPool) Stats() PoolStats {
+	pool.rwLock.RLock()
+	defer pool.rwLock.RUnlock()
+
+	stats := PoolStats{
+		IdleConns:    pool.idleConnectionQueue.Len(),
+		ActiveConns:  pool.activeConnectionQueue.Len(),
+		HostConns:    make(map[string]int, len(pool.addresses)),
+		WaitCount:    pool.waitCount,
+		WaitDuration: pool.waitDuration,
+		HostBreakers: make(map[string]BreakerState, len(pool.addresses)),
+	}
+	stats.ResultBytesInUse = int64(len(pool.resultSlots)) * int64(pool.conf.MaxResultBytes)
+	now := time.Now()
+	for _, host := range pool.addresses {
+		stats.HostBreakers[host.String()] = pool.breakerState(host, now)
+	}
+	stats.TotalConns = stats.IdleConns + stats.ActiveConns
+	for _, l := range []*list.List{&pool.idleConnectionQueue, &pool.activeConnectionQueue} {
+		for ele := l.Front(); ele != nil; ele = ele.Next() {
+			stats.HostConns[ele.Value.(*connection).severAddress.String()]++
+		}
+	}
+	return stats
+}
+
+// HostStatus is a snapshot of the status of a host of the connection pool
+type HostStatus struct {
+	Address HostAddress `json:"address"`
+	// Whether new connections can be created to the host,
+	// false if the host is in backoff after connection failures or its circuit breaker is open
+	Available   bool `json:"available"`
+	ActiveConns int  `json:"active_conns"`
+	IdleConns   int  `json:"idle_conns"`
+	// Number of consecutive failures to connect to the host and the time of the last failure,
+	// which is zero if the host never failed
+	Failures    int       `json:"failures"`
+	LastFailure time.Time `json:"last_failure"`
+}
+
+// Hosts returns a snapshot of the status of each host of the pool, in the order of the addresses
+// the pool was created with
+func (pool *ConnectionPool) Hosts() []HostStatus {
+	pool.rwLock.RLock()
+	defer pool.rwLock.RUnlock()
+
+	now := time.Now()
+	hosts := make([]HostStatus, 0, len(pool.addresses))
+	index := make(map[HostAddress]int, len(pool.addresses))
+	for _, host := range pool.addresses {
+		status := HostStatus{Address: host, Available: true}
+		if state, ok := pool.hostStates[host]; ok {
+			status.Available = !now.Before(state.retryAt) && state.breakerState(now) != BreakerOpen
+			status.Failures = state.failures
+			status.LastFailure = state.lastFailure
+		}
+		index[host] = len(hosts)
+		hosts = append(hosts, status)
+	}
+	for ele := pool.idleConnectionQueue.Front(); ele != nil; ele = ele.Next() {
+		if i, ok := index[ele.Value.(*connection).severAddress]; ok {
+			hosts[i].IdleConns++
+		}
+	}
+	for ele := pool.activeConnectionQueue.Front(); ele != nil; ele = ele.Next() {
+		if i, ok := index[ele.Value.(*connection).severAddress]; ok {
+			hosts[i].ActiveConns++
+		}
+	}
+	return hosts
 }
 
 func (pool *ConnectionPool) getActiveConnCount() int {
@@ -200,32 +886,175 @@ func (pool *ConnectionPool) getIdleConnCount() int {
 	return pool.idleConnectionQueue.Len()
 }
 
-// Get a valid host (round robin)
-func (pool *ConnectionPool) getHost() HostAddress {
-	if pool.hostIndex == len(pool.addresses) {
-		pool.hostIndex = 0
+// Return the state of the circuit breaker of the host, the caller should hold the lock
+func (pool *ConnectionPool) breakerState(host HostAddress, now time.Time) BreakerState {
+	if state, ok := pool.hostStates[host]; ok {
+		return state.breakerState(now)
+	}
+	return BreakerClosed
+}
+
+// Get a valid host with the load balancer, hosts in backoff or with an open circuit breaker
+// and excluded hosts are skipped
+func (pool *ConnectionPool) getHost(exclude ...HostAddress) (HostAddress, error) {
+	candidates, err := pool.hostCandidates(exclude...)
+	if err != nil {
+		return HostAddress{}, err
+	}
+	i := pool.conf.LoadBalancer.Select(candidates)
+	if i < 0 || i >= len(candidates) {
+		return HostAddress{}, fmt.Errorf("failed to get connection: load balancer selected invalid host index %d", i)
+	}
+	return candidates[i].Address, nil
+}
+
+// Return the loads of the hosts a new connection can be opened to as getHost, in the order of the addresses
+func (pool *ConnectionPool) hostCandidates(exclude ...HostAddress) ([]HostLoad, error) {
+	now := time.Now()
+	loads := make(map[HostAddress]*HostLoad, len(pool.addresses))
+	candidates := make([]HostLoad, 0, len(pool.addresses))
+	for _, host := range pool.addresses {
+		if state, ok := pool.hostStates[host]; ok && (now.Before(state.retryAt) || state.breakerState(now) == BreakerOpen) {
+			continue
+		}
+		if containsHost(exclude, host) {
+			continue
+		}
+		loads[host] = &HostLoad{Address: host}
+	}
+	if len(loads) == 0 && len(exclude) > 0 {
+		return nil, fmt.Errorf("failed to get connection: no host is available, the hosts which are not excluded are in backoff or their circuit breakers are open after connection failures")
+	}
+	if len(loads) == 0 {
+		return nil, fmt.Errorf("failed to get connection: all hosts are in backoff or their circuit breakers are open after connection failures")
+	}
+	for ele := pool.idleConnectionQueue.Front(); ele != nil; ele = ele.Next() {
+		if load, ok := loads[ele.Value.(*connection).severAddress]; ok {
+			load.TotalConns++
+		}
+	}
+	for ele := pool.activeConnectionQueue.Front(); ele != nil; ele = ele.Next() {
+		if load, ok := loads[ele.Value.(*connection).severAddress]; ok {
+			load.TotalConns++
+			load.ActiveConns++
+		}
+	}
+	for _, host := range pool.addresses {
+		if load, ok := loads[host]; ok {
+			if maxConns := pool.conf.MaxConnsPerHost; maxConns > 0 && load.TotalConns >= maxConns {
+				continue
+			}
+			candidates = append(candidates, *load)
+		}
+	}
+	// Every available host reached MaxConnsPerHost, the pool is saturated as if it reached its capacity
+	if len(candidates) == 0 {
+		return nil, errPoolExhausted
+	}
+	return candidates, nil
+}
+
+// Put the host in backoff after a connection failure
+func (pool *ConnectionPool) markHostFailed(host HostAddress) {
+	state, ok := pool.hostStates[host]
+	if !ok {
+		state = &hostState{}
+		pool.hostStates[host] = state
+	}
+	state.failures++
+	state.lastFailure = time.Now()
+	state.retryAt = state.lastFailure.Add(pool.conf.RetryPolicy.delay(state.failures))
+	pool.log.Warn("host is marked down after connection failures", "host", host, "failures", state.failures,
+		"retry_after", state.retryAt.Sub(state.lastFailure))
+
+	breaker := pool.conf.CircuitBreaker
+	if breaker.FailureThreshold <= 0 {
+		return
+	}
+	if state.windowFailures == 0 || state.lastFailure.Sub(state.windowStart) > breaker.Window {
+		state.windowFailures = 0
+		state.windowStart = state.lastFailure
+	}
+	state.windowFailures++
+	// A failed trial of a half-open breaker opens it again
+	if state.breakerOpen || state.windowFailures >= breaker.FailureThreshold {
+		state.breakerOpen = true
+		state.openUntil = state.lastFailure.Add(breaker.Cooldown)
+		pool.log.Warn("circuit breaker is open after connection failures, the host is skipped", "host", host,
+			"failures", state.windowFailures, "cooldown", breaker.Cooldown)
+	}
+}
+
+// Reset the failures of the host after a successful connection
+func (pool *ConnectionPool) markHostAvailable(host HostAddress) {
+	if state, ok := pool.hostStates[host]; ok && state.failures > 0 {
+		pool.log.Info("host is available again after connection failures", "host", host, "failures", state.failures)
+		state.failures = 0
+		state.retryAt = time.Time{}
+		state.windowFailures = 0
+		state.breakerOpen = false
 	}
-	host := pool.addresses[pool.hostIndex]
-	pool.hostIndex++
-	return host
 }
 
 // Select a new host to create a new connection
-func (pool *ConnectionPool) newConnToHost() (*connection, error) {
-	// Get a valid host (round robin)
-	host := pool.getHost()
-	newConn := newConnection(host)
+func (pool *ConnectionPool) newConnToHost(ctx context.Context, exclude ...HostAddress) (*connection, error) {
+	// Get a valid host with the load balancer
+	host, err := pool.getHost(exclude...)
+	if err != nil {
+		return nil, err
+	}
+	newConn := pool.buildConnection(host)
 	// Open connection to host
-	err := newConn.open(newConn.severAddress, pool.conf.TimeOut)
+	err = newConn.openWithContext(ctx, newConn.severAddress, pool.conf.TimeOut)
 	if err != nil {
+		// The host is not to blame if the caller gave up
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, fmt.Errorf("failed to open connection: %w", ctxErr)
+		}
+		pool.markHostFailed(host)
 		return nil, err
 	}
+	pool.markHostAvailable(host)
+	pool.log.Debug("opened connection", "host", host)
 	// Add connection to active queue
 	pool.activeConnectionQueue.PushBack(newConn)
 	// TODO: update workload
 	return newConn, nil
 }
 
+// Create a new connection to host with the transport options of the pool, the caller should hold the lock
+func (pool *ConnectionPool) buildConnection(host HostAddress) *connection {
+	newConn := newConnection(host)
+	newConn.dialer = pool.conf.Dialer
+	if pool.conf.LocalAddr != nil {
+		newConn.dialer = (&net.Dialer{LocalAddr: pool.conf.LocalAddr}).DialContext
+	}
+	newConn.newService = pool.conf.GraphServiceFactory
+	newConn.connTimeout = pool.conf.ConnTimeout
+	newConn.sslConfig = pool.sslConfig
+	if name, ok := pool.serverNames[host]; ok {
+		newConn.serverName = name
+	} else {
+		newConn.serverName = pool.conf.SSLServerNames[host.String()]
+	}
+	newConn.bufferSize = pool.conf.SocketBufferSize
+	newConn.checkStmt = pool.conf.HealthCheckStmt
+	newConn.maxResult = pool.conf.MaxResultBytes
+	newConn.protocol = pool.conf.ProtocolFactory
+	newConn.certPins = pool.certPins
+	return newConn
+}
+
+// Check if the host is in the list
+func containsHost(hosts []HostAddress, host HostAddress) bool {
+	for _, h := range hosts {
+		if h == host {
+			return true
+		}
+	}
+	return false
+}
+
 // Remove a connection from list
 func removeFromList(l *list.List, conn *connection) {
 	for ele := l.Front(); ele != nil; ele = ele.Next() {
@@ -236,15 +1065,14 @@ func removeFromList(l *list.List, conn *connection) {
 }
 
 // Compare total connection number with pool max size and return a connection if capable
-func (pool *ConnectionPool) createConnection() (*connection, error) {
+func (pool *ConnectionPool) createConnection(ctx context.Context, exclude ...HostAddress) (*connection, error) {
 	totalConn := pool.idleConnectionQueue.Len() + pool.activeConnectionQueue.Len()
 	// If no idle avaliable and the number of total connection reaches the max pool size, return error/wait for timeout
 	if totalConn >= pool.conf.MaxConnPoolSize {
-		return nil, fmt.Errorf("failed to get connection: No valid connection" +
-			" in the idle queue and connection number has reached the pool capacity")
+		return nil, errPoolExhausted
 	}
 
-	newConn, err := pool.newConnToHost()
+	newConn, err := pool.newConnToHost(ctx, exclude...)
 	if err != nil {
 		return nil, err
 	}
@@ -320,3 +1148,87 @@ func (pool *ConnectionPool) timeoutConnectionList() (closing []*connection) {
 	}
 	return
 }
+
+// startKeepAlive starts connectionKeepAlive if keepAliveInterval > 0.
+func (pool *ConnectionPool) startKeepAlive() {
+	if pool.conf.KeepAliveInterval > 0 && pool.keepAliveChan == nil {
+		pool.keepAliveChan = make(chan struct{}, 1)
+		go pool.connectionKeepAlive()
+	}
+}
+
+func (pool *ConnectionPool) connectionKeepAlive() {
+	d := pool.conf.KeepAliveInterval
+	t := time.NewTimer(d)
+
+	for {
+		select {
+		case <-t.C:
+		case <-pool.keepAliveChan: // pool was closed.
+		}
+
+		if !pool.pingIdleConnections() {
+			return
+		}
+		t.Reset(d)
+	}
+}
+
+// pingIdleConnections pings the connections idle for longer than keepAliveInterval and closes
+// the ones failing the ping. It returns false once the pool is closed.
+func (pool *ConnectionPool) pingIdleConnections() bool {
+	pool.rwLock.Lock()
+	if pool.closed {
+		pool.keepAliveChan = nil
+		pool.rwLock.Unlock()
+		return false
+	}
+	// The connections are held as active ones while they are pinged out of the lock,
+	// so they are not handed out and a slow host does not block the pool
+	stale := pool.staleConnectionList()
+	pool.rwLock.Unlock()
+	for _, c := range stale {
+		pool.returnPinged(c, c.ping())
+	}
+	return true
+}
+
+// staleConnectionList moves the connections idle for longer than keepAliveInterval
+// from the idle queue to the active queue.
+func (pool *ConnectionPool) staleConnectionList() (stale []*connection) {
+	idleSince := time.Now().Add(-pool.conf.KeepAliveInterval)
+	for ele := pool.idleConnectionQueue.Front(); ele != nil; {
+		next := ele.Next()
+		conn := ele.Value.(*connection)
+		if conn.returnedAt.Before(idleSince) {
+			stale = append(stale, conn)
+			pool.idleConnectionQueue.Remove(ele)
+			pool.activeConnectionQueue.PushBack(conn)
+		}
+		ele = next
+	}
+	return
+}
+
+// Put the connection pinged by the keepalive back into the idle queue, or close it if the ping failed.
+// The idle time of the connection is kept, so the ping does not prevent it from being cleaned.
+func (pool *ConnectionPool) returnPinged(conn *connection, alive bool) {
+	pool.rwLock.Lock()
+	defer pool.rwLock.Unlock()
+	if pool.closed {
+		// The connection is already closed with the active ones
+		return
+	}
+	removeFromList(&pool.activeConnectionQueue, conn)
+	if alive {
+		pool.idleConnectionQueue.PushBack(conn)
+	} else {
+		pool.log.Warn("closing idle connection after a failed keepalive ping", "host", conn.severAddress)
+		conn.close()
+	}
+	pool.notifyWaiter()
+	if pool.drainedChan != nil && pool.activeConnectionQueue.Len() == 0 {
+		close(pool.drainedChan)
+		pool.drainedChan = nil
+	}
+}
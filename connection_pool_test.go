@@ -0,0 +1,598 @@
+/* Copyright (c) 2021 vesoft inc. All rights reserved.
+ *
+ * This source code is licensed under Apache 2.0 License,
+ * attached with Common Clause Condition 1.0, found in the LICENSES directory.
+ */
+
+package nebula_go
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vesoft-inc/nebula-go/v2/fake"
+	"github.com/vesoft-inc/nebula-go/v2/nebula"
+	"github.com/vesoft-inc/nebula-go/v2/nebula/graph"
+)
+
+func TestWaitIdleConn(t *testing.T) {
+	pool := &ConnectionPool{
+		conf: PoolConfig{MaxConnPoolSize: 1, MaxConnIdleBeforeCheck: time.Hour},
+		log:  NoopStructuredLogger{},
+	}
+	conn := &connection{severAddress: HostAddress{"127.0.0.1", 3699}}
+	pool.activeConnectionQueue.PushBack(conn)
+
+	// Timed out while the only connection is active
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := pool.waitIdleConn(ctx)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Equal(t, 0, pool.waiters.Len())
+
+	// Woken up when the connection is released
+	acquired := make(chan *connection)
+	go func() {
+		conn, err := pool.waitIdleConn(context.Background())
+		assert.Nil(t, err)
+		acquired <- conn
+	}()
+	for {
+		pool.rwLock.Lock()
+		waiting := pool.waiters.Len()
+		pool.rwLock.Unlock()
+		if waiting > 0 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	pool.release(conn)
+	select {
+	case got := <-acquired:
+		assert.Equal(t, conn, got)
+	case <-time.After(time.Second):
+		t.Fatal("waiter is not woken up by the released connection")
+	}
+	assert.Equal(t, 1, pool.getActiveConnCount())
+	assert.Equal(t, 0, pool.getIdleConnCount())
+}
+
+func TestHosts(t *testing.T) {
+	hosts := []HostAddress{{"127.0.0.1", 3699}, {"127.0.0.1", 3700}}
+	pool := &ConnectionPool{
+		addresses:  hosts,
+		conf:       PoolConfig{RetryPolicy: RetryPolicy{InitialDelay: time.Hour, MaxDelay: time.Hour, Multiplier: 2}},
+		log:        NoopStructuredLogger{},
+		hostStates: make(map[HostAddress]*hostState),
+	}
+	pool.idleConnectionQueue.PushBack(&connection{severAddress: hosts[0]})
+	pool.activeConnectionQueue.PushBack(&connection{severAddress: hosts[0]})
+	pool.activeConnectionQueue.PushBack(&connection{severAddress: hosts[0]})
+	pool.markHostFailed(hosts[1])
+
+	statuses := pool.Hosts()
+	assert.Len(t, statuses, 2)
+	assert.Equal(t, HostStatus{Address: hosts[0], Available: true, ActiveConns: 2, IdleConns: 1}, statuses[0])
+	assert.Equal(t, hosts[1], statuses[1].Address)
+	assert.False(t, statuses[1].Available)
+	assert.Equal(t, 1, statuses[1].Failures)
+	assert.False(t, statuses[1].LastFailure.IsZero())
+}
+
+func TestGetSessionWithCredentialsError(t *testing.T) {
+	pool := &ConnectionPool{
+		conf: PoolConfig{MaxConnPoolSize: 1, MaxConnIdleBeforeCheck: time.Hour},
+		log:  NoopStructuredLogger{},
+	}
+	pool.idleConnectionQueue.PushBack(&connection{severAddress: HostAddress{"127.0.0.1", 3699}, returnedAt: time.Now()})
+
+	providerErr := errors.New("token expired")
+	_, err := pool.GetSessionWithCredentials(func() (string, string, error) {
+		return "", "", providerErr
+	})
+	assert.True(t, errors.Is(err, providerErr))
+	// The connection is put back to the pool
+	assert.Equal(t, 0, pool.getActiveConnCount())
+	assert.Equal(t, 1, pool.getIdleConnCount())
+}
+
+func TestHostsWithoutLabel(t *testing.T) {
+	hosts := []HostAddress{{"127.0.0.1", 3699}, {"127.0.0.1", 3700}, {"127.0.0.1", 3701}}
+	pool := &ConnectionPool{
+		addresses: hosts,
+		hostLabels: map[HostAddress][]string{
+			hosts[0]: {"tenant-a"},
+			hosts[1]: {"tenant-a", "tenant-b"},
+		},
+	}
+	without, err := pool.hostsWithoutLabel("tenant-a")
+	assert.Nil(t, err)
+	assert.Equal(t, []HostAddress{hosts[2]}, without)
+
+	without, err = pool.hostsWithoutLabel("tenant-b")
+	assert.Nil(t, err)
+	assert.Equal(t, []HostAddress{hosts[0], hosts[2]}, without)
+
+	without, err = pool.hostsWithoutLabel("")
+	assert.Nil(t, err)
+	assert.Nil(t, without)
+
+	_, err = pool.hostsWithoutLabel("tenant-c")
+	assert.EqualError(t, err, "failed to get connection: no host carries the label tenant-c")
+}
+
+func TestMaxConnLifetime(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	host := HostAddress{Host: "127.0.0.1", Port: listener.Addr().(*net.TCPAddr).Port}
+	pool := &ConnectionPool{
+		addresses: []HostAddress{host},
+		conf: PoolConfig{
+			TimeOut:                time.Second,
+			MaxConnPoolSize:        1,
+			MaxConnIdleBeforeCheck: time.Hour,
+			MaxConnLifetime:        time.Hour,
+			LoadBalancer:           &RoundRobin{},
+		},
+		log:        NoopStructuredLogger{},
+		hostStates: make(map[HostAddress]*hostState),
+	}
+	defer pool.Close()
+
+	// An expired idle connection is replaced by a new connection
+	conn, err := pool.getIdleConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool.release(conn)
+	conn.openedAt = time.Now().Add(-2 * time.Hour)
+	newConn, err := pool.getIdleConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotEqual(t, conn, newConn)
+	assert.Equal(t, 1, pool.getActiveConnCount())
+	assert.Equal(t, 0, pool.getIdleConnCount())
+
+	// An expired connection is closed when it is returned
+	newConn.openedAt = time.Now().Add(-2 * time.Hour)
+	pool.release(newConn)
+	assert.Equal(t, 0, pool.getActiveConnCount())
+	assert.Equal(t, 0, pool.getIdleConnCount())
+
+	// and replaced by a new idle connection if the pool would go below its min size
+	pool.conf.MinConnPoolSize = 1
+	conn, err = pool.getIdleConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.openedAt = time.Now().Add(-2 * time.Hour)
+	pool.release(conn)
+	assert.Equal(t, 0, pool.getActiveConnCount())
+	assert.Equal(t, 1, pool.getIdleConnCount())
+	assert.NotEqual(t, conn, pool.idleConnectionQueue.Front().Value)
+}
+
+func TestGetSessionContextAuthTimeout(t *testing.T) {
+	// The server accepts the connection but never answers the authentication
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+	port := listener.Addr().(*net.TCPAddr).Port
+	host := HostAddress{"127.0.0.1", port}
+	pool := &ConnectionPool{
+		addresses: []HostAddress{host},
+		conf: PoolConfig{
+			MaxConnPoolSize: 1,
+			LoadBalancer:    &RoundRobin{},
+			RetryPolicy:     RetryPolicy{InitialDelay: time.Hour, MaxDelay: time.Hour, Multiplier: 2},
+		},
+		log:        NoopStructuredLogger{},
+		hostStates: make(map[HostAddress]*hostState),
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = pool.GetSessionContext(ctx, "root", "nebula")
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.True(t, time.Since(start) < 5*time.Second)
+	// The connection is put back and the host is not blamed for the timeout
+	assert.Equal(t, 0, pool.getActiveConnCount())
+	assert.Equal(t, 1, pool.getIdleConnCount())
+	assert.True(t, pool.Hosts()[0].Available)
+	pool.Close()
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	hosts := []HostAddress{{"127.0.0.1", 3699}, {"127.0.0.1", 3700}}
+	pool := &ConnectionPool{
+		addresses: hosts,
+		conf: PoolConfig{
+			LoadBalancer:   &RoundRobin{},
+			CircuitBreaker: CircuitBreaker{FailureThreshold: 3, Window: time.Minute, Cooldown: time.Hour},
+		},
+		log:        NoopStructuredLogger{},
+		hostStates: make(map[HostAddress]*hostState),
+	}
+	pool.idleConnectionQueue.PushBack(&connection{severAddress: hosts[0], returnedAt: time.Now()})
+
+	pool.markHostFailed(hosts[0])
+	pool.markHostFailed(hosts[0])
+	assert.Equal(t, BreakerClosed, pool.Stats().HostBreakers[hosts[0].String()])
+	pool.markHostFailed(hosts[0])
+	assert.Equal(t, BreakerOpen, pool.Stats().HostBreakers[hosts[0].String()])
+	assert.Equal(t, BreakerClosed, pool.Stats().HostBreakers[hosts[1].String()])
+	assert.False(t, pool.Hosts()[0].Available)
+
+	// The host is skipped entirely, including its idle connection
+	for i := 0; i < 2; i++ {
+		host, err := pool.getHost()
+		assert.Nil(t, err)
+		assert.Equal(t, hosts[1], host)
+	}
+	_, err := pool.getHost(hosts[1])
+	assert.NotNil(t, err)
+	pool.conf.MaxConnPoolSize = 1
+	_, err = pool.getIdleConn()
+	assert.Equal(t, errPoolExhausted, err)
+
+	// A failed trial of the half-open breaker opens it again
+	pool.hostStates[hosts[0]].openUntil = time.Now()
+	assert.Equal(t, BreakerHalfOpen, pool.Stats().HostBreakers[hosts[0].String()])
+	host, err := pool.getHost(hosts[1])
+	assert.Nil(t, err)
+	assert.Equal(t, hosts[0], host)
+	pool.markHostFailed(hosts[0])
+	assert.Equal(t, BreakerOpen, pool.Stats().HostBreakers[hosts[0].String()])
+
+	// A succeeded trial closes it
+	pool.hostStates[hosts[0]].openUntil = time.Now()
+	pool.markHostAvailable(hosts[0])
+	assert.Equal(t, BreakerClosed, pool.Stats().HostBreakers[hosts[0].String()])
+
+	// Failures out of the window are not counted together
+	pool.markHostFailed(hosts[0])
+	pool.markHostFailed(hosts[0])
+	pool.hostStates[hosts[0]].windowStart = time.Now().Add(-2 * time.Minute)
+	pool.markHostFailed(hosts[0])
+	assert.Equal(t, BreakerClosed, pool.Stats().HostBreakers[hosts[0].String()])
+}
+
+func TestLocalAddr(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	remote := make(chan net.Addr, 1)
+	go func() {
+		if conn, err := listener.Accept(); err == nil {
+			remote <- conn.RemoteAddr()
+			conn.Close()
+		}
+	}()
+	// Find a free local port
+	free, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	localAddr := free.Addr().(*net.TCPAddr)
+	free.Close()
+
+	pool := &ConnectionPool{conf: PoolConfig{LocalAddr: localAddr}}
+	host := HostAddress{Host: "127.0.0.1", Port: listener.Addr().(*net.TCPAddr).Port}
+	conn := pool.buildConnection(host)
+	if err = conn.open(host, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	defer conn.close()
+	select {
+	case addr := <-remote:
+		assert.Equal(t, localAddr.String(), addr.String())
+	case <-time.After(time.Second):
+		t.Fatal("connection is not accepted")
+	}
+}
+
+func TestReserveResult(t *testing.T) {
+	pool := &ConnectionPool{
+		conf:        PoolConfig{MaxResultBytes: 1 << 20, MaxTotalResultBytes: 2<<20 + 1},
+		resultSlots: make(chan struct{}, 2),
+	}
+	release1, err := pool.reserveResult(context.Background())
+	assert.Nil(t, err)
+	release2, err := pool.reserveResult(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, int64(2<<20), pool.Stats().ResultBytesInUse)
+
+	// Waits until ctx is done while the limit is reached
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = pool.reserveResult(ctx)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+
+	release1()
+	release3, err := pool.reserveResult(context.Background())
+	assert.Nil(t, err)
+	release2()
+	release3()
+	assert.Equal(t, int64(0), pool.Stats().ResultBytesInUse)
+
+	// Unlimited
+	release, err := (&ConnectionPool{}).reserveResult(context.Background())
+	assert.Nil(t, err)
+	release()
+}
+
+func TestCheckSpace(t *testing.T) {
+	service := fake.NewGraphService()
+	service.SetUser("root", "nebula")
+	service.SetResult("SHOW SPACES", []string{"Name"},
+		[]*nebula.Value{{SVal: []byte("basketballplayer")}}, []*nebula.Value{{SVal: []byte("test")}})
+
+	pool := newFakePool(t, service)
+	defer pool.Close()
+
+	// SpaceName is not set
+	assert.NotNil(t, pool.CheckSpace("root", "nebula"))
+
+	pool.conf.SpaceName = "test"
+	assert.Nil(t, pool.CheckSpace("root", "nebula"))
+	assert.NotNil(t, pool.CheckSpace("root", "wrong"))
+	pool.conf.SpaceName = "tset"
+	err := pool.CheckSpace("root", "nebula")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "the space tset of the pool config does not exist")
+	// The space is not used and the sessions are signed out
+	assert.Equal(t, []string{"SHOW SPACES", "SHOW SPACES"}, service.Executed())
+	assert.Equal(t, 0, service.Sessions())
+}
+
+func TestMaxConnsPerHost(t *testing.T) {
+	hosts := []HostAddress{{"127.0.0.1", 3699}, {"127.0.0.2", 3699}}
+	pool := &ConnectionPool{
+		addresses:  hosts,
+		conf:       PoolConfig{MaxConnPoolSize: 10, MaxConnsPerHost: 2, LoadBalancer: &RoundRobin{}},
+		log:        NoopStructuredLogger{},
+		hostStates: make(map[HostAddress]*hostState),
+	}
+	pool.activeConnectionQueue.PushBack(&connection{severAddress: hosts[0]})
+	pool.idleConnectionQueue.PushBack(&connection{severAddress: hosts[0]})
+	pool.activeConnectionQueue.PushBack(&connection{severAddress: hosts[1]})
+	// The first host reached the cap, the new connections spill to the second one
+	for i := 0; i < 3; i++ {
+		host, err := pool.getHost()
+		assert.Nil(t, err)
+		assert.Equal(t, hosts[1], host)
+	}
+	pool.activeConnectionQueue.PushBack(&connection{severAddress: hosts[1]})
+	_, err := pool.getHost()
+	assert.Equal(t, errPoolExhausted, err)
+
+	_, err = NewConnectionPool(hosts, PoolConfig{MaxConnsPerHost: 1, MinConnPoolSize: 3}, NoopLogger{})
+	assert.NotNil(t, err)
+}
+
+func TestHostDiscovery(t *testing.T) {
+	dnsHost := HostAddress{"graphd.nebula", 3699}
+	static := HostAddress{"127.0.0.1", 3699}
+	records := []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2"), net.ParseIP("10.0.0.1")}
+	lookupIP := func(host string) ([]net.IP, error) {
+		if host != dnsHost.Host {
+			return nil, errors.New("no such host")
+		}
+		return records, nil
+	}
+	discovered, err := lookupHostIPs(lookupIP, dnsHost)
+	assert.Nil(t, err)
+	assert.Equal(t, []HostAddress{{"10.0.0.1", 3699}, {"10.0.0.2", 3699}}, discovered)
+	_, err = lookupHostIPs(lookupIP, HostAddress{"unknown", 3699})
+	assert.NotNil(t, err)
+	records = nil
+	_, err = lookupHostIPs(lookupIP, dnsHost)
+	assert.NotNil(t, err)
+
+	pool := &ConnectionPool{
+		addresses:       []HostAddress{static},
+		staticAddresses: []HostAddress{static},
+		conf:            PoolConfig{DNSHost: dnsHost},
+		sslConfig:       &tls.Config{},
+		log:             NoopStructuredLogger{},
+		hostStates:      make(map[HostAddress]*hostState),
+	}
+	assert.Nil(t, pool.setDiscoveredHosts(discovered))
+	assert.Equal(t, []HostAddress{static, {"10.0.0.1", 3699}, {"10.0.0.2", 3699}}, pool.addresses)
+	assert.Equal(t, "graphd.nebula", pool.serverNames[HostAddress{"10.0.0.2", 3699}])
+
+	// The removed host is drained, the static host is kept
+	removed := HostAddress{"10.0.0.2", 3699}
+	pool.hostStates[removed] = &hostState{}
+	idle := &connection{severAddress: removed}
+	pool.idleConnectionQueue.PushBack(idle)
+	pool.idleConnectionQueue.PushBack(&connection{severAddress: static})
+	active := &connection{severAddress: removed}
+	pool.activeConnectionQueue.PushBack(active)
+	closing := pool.setDiscoveredHosts([]HostAddress{{"10.0.0.1", 3699}, {"10.0.0.3", 3699}, static})
+	assert.Equal(t, []*connection{idle}, closing)
+	assert.Equal(t, []HostAddress{static, {"10.0.0.1", 3699}, {"10.0.0.3", 3699}}, pool.addresses)
+	assert.Equal(t, 1, pool.idleConnectionQueue.Len())
+	assert.NotContains(t, pool.hostStates, removed)
+	assert.NotContains(t, pool.serverNames, removed)
+	assert.False(t, containsHost(pool.addresses, active.severAddress))
+}
+
+func TestGetSessionWithAffinity(t *testing.T) {
+	hosts := []HostAddress{{"127.0.0.1", 3699}, {"127.0.0.2", 3699}, {"127.0.0.3", 3699}}
+	down := false
+	conf := GetDefaultConf()
+	conf.GraphServiceFactory = func(host HostAddress) (GraphService, error) {
+		if down && host == hosts[1] {
+			return nil, errors.New("connection refused")
+		}
+		return fake.NewGraphService(), nil
+	}
+	pool, err := NewConnectionPool(hosts, conf, NoopLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	down = true
+
+	available := []HostLoad{{Address: hosts[0]}, {Address: hosts[2]}}
+	for i := 0; i < 10; i++ {
+		key := fmt.Sprintf("key%d", i)
+		for j := 0; j < 2; j++ {
+			session, err := pool.GetSessionWithAffinity(key, "root", "nebula")
+			if err != nil {
+				t.Fatal(err)
+			}
+			// The keys of the failed host are rehashed to the available ones
+			assert.Equal(t, available[RendezvousHash{}.Select(key, available)].Address,
+				session.connection.severAddress, key)
+			session.Release()
+		}
+	}
+}
+
+func TestIdleOrder(t *testing.T) {
+	host := HostAddress{"127.0.0.1", 3699}
+	for _, order := range []IdleOrder{"", IdleFIFO, IdleLIFO} {
+		pool := &ConnectionPool{
+			addresses:  []HostAddress{host},
+			conf:       PoolConfig{MaxConnPoolSize: 10, MaxConnIdleBeforeCheck: time.Hour, IdleOrder: order},
+			log:        NoopStructuredLogger{},
+			hostStates: make(map[HostAddress]*hostState),
+		}
+		var conns []*connection
+		for i := 0; i < 3; i++ {
+			conn := newConnection(host)
+			conns = append(conns, conn)
+			pool.idleConnectionQueue.PushBack(conn)
+		}
+		conn, err := pool.getIdleConn()
+		assert.Nil(t, err)
+		if order == IdleLIFO {
+			assert.Equal(t, conns[2], conn, order)
+		} else {
+			assert.Equal(t, conns[0], conn, order)
+		}
+		assert.Equal(t, 2, pool.getIdleConnCount())
+	}
+}
+
+// A graph service whose requests wait for the release and fail if the host is down
+type pingService struct {
+	*fake.GraphService
+	down    bool
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *pingService) Execute(sessionId int64, stmt []byte) (*graph.ExecutionResponse, error) {
+	s.started <- struct{}{}
+	<-s.release
+	if s.down {
+		return nil, errors.New("connection reset by peer")
+	}
+	return s.GraphService.Execute(sessionId, stmt)
+}
+
+func TestKeepAlivePing(t *testing.T) {
+	host := HostAddress{"127.0.0.1", 3699}
+	pool := &ConnectionPool{
+		addresses:  []HostAddress{host},
+		conf:       PoolConfig{MaxConnPoolSize: 10, KeepAliveInterval: time.Minute},
+		log:        NoopStructuredLogger{},
+		hostStates: make(map[HostAddress]*hostState),
+	}
+	started, release := make(chan struct{}, 2), make(chan struct{})
+	alive := &connection{severAddress: host, returnedAt: time.Now().Add(-time.Hour),
+		graph: &pingService{GraphService: fake.NewGraphService(), started: started, release: release}}
+	dead := &connection{severAddress: host, returnedAt: time.Now().Add(-time.Hour),
+		graph: &pingService{GraphService: fake.NewGraphService(), down: true, started: started, release: release}}
+	recent := &connection{severAddress: host, returnedAt: time.Now(), graph: fake.NewGraphService()}
+	for _, conn := range []*connection{alive, recent, dead} {
+		pool.idleConnectionQueue.PushBack(conn)
+	}
+
+	done := make(chan bool)
+	go func() {
+		done <- pool.pingIdleConnections()
+	}()
+	<-started
+	// The pool is not locked during the pings and the pinged connections are not handed out
+	stats := pool.Stats()
+	assert.Equal(t, 1, stats.IdleConns)
+	assert.Equal(t, 2, stats.ActiveConns)
+	close(release)
+	assert.True(t, <-done)
+	assert.Equal(t, 0, pool.getActiveConnCount())
+	assert.Equal(t, 2, pool.getIdleConnCount())
+	assert.Equal(t, recent, pool.idleConnectionQueue.Front().Value)
+	assert.Equal(t, alive, pool.idleConnectionQueue.Back().Value)
+
+	pool.Close()
+	assert.False(t, pool.pingIdleConnections())
+}
+
+func TestLabelsWithDiscovery(t *testing.T) {
+	labeled := HostAddress{"127.0.0.1", 3699}
+	pool := newFakePool(t, fake.NewGraphService(), func(conf *PoolConfig) {
+		conf.HostLabels = map[string][]string{labeled.String(): {"tenant-a"}}
+	})
+	defer pool.Close()
+	// The discovered hosts change on each refresh while the sessions look up the labeled hosts
+	var refreshes uint32
+	pool.lookupIP = func(host string) ([]net.IP, error) {
+		if atomic.AddUint32(&refreshes, 1)%2 == 0 {
+			return []net.IP{net.ParseIP("10.0.0.1")}, nil
+		}
+		return []net.IP{net.ParseIP("10.0.0.2"), net.ParseIP("10.0.0.3")}, nil
+	}
+	pool.conf.DNSHost = HostAddress{"graphd.nebula", 3699}
+	pool.conf.DNSRefreshInterval = time.Millisecond
+	pool.startDiscovery()
+
+	for i := 0; i < 50; i++ {
+		session, err := pool.GetSessionWithLabel("tenant-a", "root", "nebula")
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, labeled, session.connection.severAddress)
+		session.Release()
+		time.Sleep(100 * time.Microsecond)
+	}
+	assert.True(t, atomic.LoadUint32(&refreshes) > 1)
+}
@@ -0,0 +1,257 @@
+/* Copyright (c) 2021 vesoft inc. All rights reserved.
+ *
+ * This source code is licensed under Apache 2.0 License,
+ * attached with Common Clause Condition 1.0, found in the LICENSES directory.
+ */
+
+package nebula_go
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/facebook/fbthrift/thrift/lib/go/thrift"
+	"github.com/stretchr/testify/assert"
+	"github.com/vesoft-inc/nebula-go/v2/fake"
+	"github.com/vesoft-inc/nebula-go/v2/nebula"
+)
+
+func TestConnectionTLSConfig(t *testing.T) {
+	sslConfig := &tls.Config{ServerName: "default.example.com"}
+	conn := newConnection(HostAddress{Host: "127.0.0.1", Port: 9669})
+	conn.sslConfig = sslConfig
+	assert.Equal(t, sslConfig, conn.tlsConfig())
+
+	conn.serverName = "graphd.example.com"
+	assert.Equal(t, "graphd.example.com", conn.tlsConfig().ServerName)
+	// The config of the pool is not modified
+	assert.Equal(t, "default.example.com", sslConfig.ServerName)
+}
+
+func TestConnectionOpenIPv6(t *testing.T) {
+	listener, err := net.Listen("tcp", "[::1]:0")
+	if err != nil {
+		t.Skipf("IPv6 is not available, %s", err.Error())
+	}
+	defer listener.Close()
+	go func() {
+		if conn, err := listener.Accept(); err == nil {
+			conn.Close()
+		}
+	}()
+
+	port := listener.Addr().(*net.TCPAddr).Port
+	host := HostAddress{Host: "::1", Port: port}
+	assert.Equal(t, fmt.Sprintf("[::1]:%d", port), host.String())
+
+	addresses, err := DomainToIP([]HostAddress{host})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []HostAddress{host}, addresses)
+
+	conn := newConnection(host)
+	if err = conn.open(host, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	conn.close()
+}
+
+func TestConnectionOpenSslTimeout(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	// The server accepts the connection but never answers the handshake
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		if conn, err := listener.Accept(); err == nil {
+			accepted <- conn
+		}
+	}()
+
+	host := HostAddress{Host: "127.0.0.1", Port: listener.Addr().(*net.TCPAddr).Port}
+	conn := newConnection(host)
+	conn.sslConfig = &tls.Config{InsecureSkipVerify: true}
+	conn.connTimeout = 100 * time.Millisecond
+	start := time.Now()
+	err = conn.open(host, time.Minute)
+	assert.NotNil(t, err)
+	assert.True(t, time.Since(start) < 5*time.Second)
+	(<-accepted).Close()
+
+	// The handshake is aborted by the cancellation of ctx
+	go func() {
+		if conn, err := listener.Accept(); err == nil {
+			accepted <- conn
+		}
+	}()
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	conn = newConnection(host)
+	conn.sslConfig = &tls.Config{InsecureSkipVerify: true}
+	start = time.Now()
+	err = conn.openWithContext(ctx, host, time.Minute)
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.True(t, time.Since(start) < 5*time.Second)
+	(<-accepted).Close()
+}
+
+type countingProtocolFactory struct {
+	thrift.ProtocolFactory
+	count int
+}
+
+func (f *countingProtocolFactory) GetProtocol(trans thrift.Transport) thrift.Protocol {
+	f.count++
+	return f.ProtocolFactory.GetProtocol(trans)
+}
+
+func TestConnectionProtocolFactory(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		if conn, err := listener.Accept(); err == nil {
+			conn.Close()
+		}
+	}()
+
+	host := HostAddress{Host: "127.0.0.1", Port: listener.Addr().(*net.TCPAddr).Port}
+	pf := &countingProtocolFactory{ProtocolFactory: thrift.NewBinaryProtocolFactoryDefault()}
+	conn := newConnection(host)
+	conn.protocol = pf
+	if err = conn.open(host, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	conn.close()
+	assert.NotZero(t, pf.count)
+}
+
+func TestGraphServiceFactory(t *testing.T) {
+	service := fake.NewGraphService()
+	service.SetUser("root", "nebula")
+	service.SetResult("RETURN 1 AS one", []string{"one"}, []*nebula.Value{{IVal: new(int64)}})
+	service.SetError("RETURN", nebula.ErrorCode_E_SYNTAX_ERROR, "syntax error near `RETURN'")
+
+	pool := newFakePool(t, service)
+	defer pool.Close()
+
+	_, err := pool.GetSession("root", "wrong")
+	assert.NotNil(t, err)
+	session, err := pool.GetSession("root", "nebula")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resSet, err := session.ExecuteAndCheck("RETURN 1 AS one")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"one"}, resSet.GetColNames())
+	assert.Equal(t, 1, resSet.GetRowSize())
+	_, err = session.ExecuteAndCheck("RETURN")
+	assert.NotNil(t, err)
+	// The connection is reopened with a new client
+	assert.Nil(t, session.connection.reopen())
+	_, err = session.ExecuteAndCheck("YIELD 1")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"RETURN 1 AS one", "RETURN", "YIELD 1"}, service.Executed())
+
+	session.Release()
+	assert.Equal(t, 0, service.Sessions())
+}
+
+// newFakeSession returns a session of a pool of a single host whose connections use the graph service,
+// the config is modified by configure before the pool is created. The returned function releases
+// the session and closes the pool.
+func newFakeSession(t *testing.T, service GraphService, configure ...func(conf *PoolConfig)) (*Session, func()) {
+	t.Helper()
+	pool := newFakePool(t, service, configure...)
+	session, err := pool.GetSession("root", "nebula")
+	if err != nil {
+		pool.Close()
+		t.Fatal(err)
+	}
+	return session, func() {
+		session.Release()
+		pool.Close()
+	}
+}
+
+// newFakePool creates a pool of a single host whose connections use the graph service,
+// the config is modified by configure before the pool is created
+func newFakePool(t *testing.T, service GraphService, configure ...func(conf *PoolConfig)) *ConnectionPool {
+	t.Helper()
+	conf := GetDefaultConf()
+	conf.GraphServiceFactory = func(host HostAddress) (GraphService, error) {
+		return service, nil
+	}
+	for _, c := range configure {
+		c(&conf)
+	}
+	pool, err := NewConnectionPool([]HostAddress{{"127.0.0.1", 3699}}, conf, NoopLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pool
+}
+
+func TestOversizedResponse(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	accepted := make(chan struct{}, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- struct{}{}
+			go func() {
+				defer conn.Close()
+				// Reply to each request with the header of a 1MB frame
+				buf := make([]byte, 4096)
+				for {
+					if _, err := conn.Read(buf); err != nil {
+						return
+					}
+					if _, err := conn.Write([]byte{0, 0x10, 0, 0}); err != nil {
+						return
+					}
+				}
+			}()
+		}
+	}()
+
+	host := HostAddress{Host: "127.0.0.1", Port: listener.Addr().(*net.TCPAddr).Port}
+	conn := newConnection(host)
+	conn.maxResult = 1024
+	if err = conn.open(host, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	defer conn.close()
+	<-accepted
+	_, err = conn.execute(1, "YIELD 1")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "exceeds the max result size")
+	_, err = conn.executeJson(1, "YIELD 1")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "exceeds the max result size")
+	// The transport is reopened after each oversized response
+	for i := 0; i < 2; i++ {
+		select {
+		case <-accepted:
+		case <-time.After(time.Second):
+			t.Fatal("connection is not reopened")
+		}
+	}
+}
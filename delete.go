@@ -0,0 +1,88 @@
+/* Copyright (c) 2021 vesoft inc. All rights reserved.
+ *
+ * This source code is licensed under Apache 2.0 License,
+ * attached with Common Clause Condition 1.0, found in the LICENSES directory.
+ */
+
+package nebula_go
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The vertices deleted per statement by DeleteVertices if no chunk size is given
+const defaultDeleteChunkSize = 500
+
+// DeleteVertices deletes the vertices of the space and their edges with DELETE VERTEX statements
+// of at most chunkSize vertices each, a chunkSize <= 0 means the default of 500.
+// The vertex IDs are checked and quoted according to the VID type of the space, see VIDType:
+// they must be integers in an INT64 space and strings no longer than the fixed length otherwise.
+// Deleting a single tag of the vertices is not supported by the DELETE TAG of Nebula Graph 2.0.
+//
+// The session is switched to the space, and switched back to its current space afterwards if it has one.
+// The chunks are executed in order and the result of each succeeded chunk is returned. The chunks after
+// a failed one are not executed, the returned error names the vertices of the failed chunk.
+// The results are returned with an error if the session fails to switch back.
+func (session *Session) DeleteVertices(space string, vids []interface{}, chunkSize int) (results []*ResultSet, err error) {
+	vidType, err := session.VIDType(space)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete vertices, %w", err)
+	}
+	literals := make([]string, len(vids))
+	for i, vid := range vids {
+		if literals[i], err = vidLiteralOfType(vid, vidType); err != nil {
+			return nil, fmt.Errorf("failed to delete vertices: the vertex at index %d, %s", i, err.Error())
+		}
+	}
+	if len(literals) == 0 {
+		return nil, nil
+	}
+	if prevSpace := session.CurrentSpace(); prevSpace != space {
+		if err = session.Use(space); err != nil {
+			return nil, fmt.Errorf("failed to delete vertices, %w", err)
+		}
+		if prevSpace != "" {
+			defer func() {
+				if useErr := session.Use(prevSpace); useErr != nil && err == nil {
+					err = fmt.Errorf("failed to switch back after deleting vertices, %w", useErr)
+				}
+			}()
+		}
+	}
+	if chunkSize <= 0 {
+		chunkSize = defaultDeleteChunkSize
+	}
+	for start := 0; start < len(literals); start += chunkSize {
+		end := start + chunkSize
+		if end > len(literals) {
+			end = len(literals)
+		}
+		resSet, err := session.ExecuteAndCheck("DELETE VERTEX " + strings.Join(literals[start:end], ", "))
+		if err != nil {
+			return results, fmt.Errorf("failed to delete the vertices at index %d to %d, %w", start, end-1, err)
+		}
+		results = append(results, resSet)
+	}
+	return results, nil
+}
+
+// Format a vertex ID which must match the VID type of the space
+func vidLiteralOfType(vid interface{}, vidType VIDType) (string, error) {
+	value, err := toNebulaValue(vid)
+	if err != nil {
+		return "", fmt.Errorf("vertex ID: %s", err.Error())
+	}
+	if vidType.IsInt && !value.IsSetIVal() {
+		return "", fmt.Errorf("vertex ID: unsupported type %T, must be an integer in a space of VID type %s",
+			vid, vidType)
+	}
+	if !vidType.IsInt && !value.IsSetSVal() {
+		return "", fmt.Errorf("vertex ID: unsupported type %T, must be a string in a space of VID type %s",
+			vid, vidType)
+	}
+	if !vidType.IsInt && len(value.GetSVal()) > vidType.Length {
+		return "", fmt.Errorf("vertex ID: %d bytes exceed the VID type %s", len(value.GetSVal()), vidType)
+	}
+	return valueLiteral(value)
+}
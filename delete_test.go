@@ -0,0 +1,76 @@
+/* Copyright (c) 2021 vesoft inc. All rights reserved.
+ *
+ * This source code is licensed under Apache 2.0 License,
+ * attached with Common Clause Condition 1.0, found in the LICENSES directory.
+ */
+
+package nebula_go
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vesoft-inc/nebula-go/v2/fake"
+	"github.com/vesoft-inc/nebula-go/v2/nebula"
+	"github.com/vesoft-inc/nebula-go/v2/nebula/graph"
+)
+
+func TestDeleteVertices(t *testing.T) {
+	service := fake.NewGraphService()
+	for space, vidType := range map[string]string{"ints": "INT64", "strings": "FIXED_STRING(4)"} {
+		service.SetResult("DESCRIBE SPACE `"+space+"`", []string{"Name", "Vid Type"},
+			[]*nebula.Value{{SVal: []byte(space)}, {SVal: []byte(vidType)}})
+		service.SetResponse("USE `"+space+"`", &graph.ExecutionResponse{
+			ErrorCode: nebula.ErrorCode_SUCCEEDED,
+			SpaceName: []byte(space),
+		})
+	}
+	service.SetError("DELETE VERTEX 5", nebula.ErrorCode_E_EXECUTION_ERROR, "Storage Error")
+
+	session, closeSession := newFakeSession(t, service)
+	defer closeSession()
+
+	results, err := session.DeleteVertices("ints", []interface{}{1, int64(2), uint8(3)}, 2)
+	assert.Nil(t, err)
+	assert.Len(t, results, 2)
+	assert.Equal(t, "ints", session.CurrentSpace())
+	// The session is switched back to its space
+	results, err = session.DeleteVertices("strings", []interface{}{"a\"b", "abcd"}, 0)
+	assert.Nil(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, "ints", session.CurrentSpace())
+	assert.Equal(t, []string{
+		"DESCRIBE SPACE `ints`", "USE `ints`", "DELETE VERTEX 1, 2", "DELETE VERTEX 3",
+		"DESCRIBE SPACE `strings`", "USE `strings`", "DELETE VERTEX \"a\\\"b\", \"abcd\"", "USE `ints`",
+	}, service.Executed())
+
+	// The chunks after a failed one are not executed
+	results, err = session.DeleteVertices("ints", []interface{}{4, 5, 6}, 1)
+	assert.Len(t, results, 1)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "index 1 to 1")
+	assert.Equal(t, "DELETE VERTEX 5", service.Executed()[len(service.Executed())-1])
+
+	// The vertex IDs must match the VID type
+	invalid := map[string][]interface{}{
+		"ints":    {1, "2"},
+		"strings": {"abcde"},
+	}
+	for space, vids := range invalid {
+		_, err = session.DeleteVertices(space, vids, 0)
+		assert.NotNil(t, err, space)
+	}
+	_, err = session.DeleteVertices("strings", []interface{}{1}, 0)
+	assert.NotNil(t, err)
+	results, err = session.DeleteVertices("ints", nil, 0)
+	assert.Nil(t, err)
+	assert.Nil(t, results)
+
+	// The results are returned with the error of switching back
+	service.SetError("USE `ints`", nebula.ErrorCode_E_SPACE_NOT_FOUND, "SpaceNotFound")
+	results, err = session.DeleteVertices("strings", []interface{}{"abcd"}, 0)
+	assert.Len(t, results, 1)
+	assert.True(t, errors.Is(err, ErrSpaceNotFound))
+	assert.Contains(t, err.Error(), "failed to switch back")
+}
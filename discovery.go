@@ -0,0 +1,130 @@
+/* Copyright (c) 2021 vesoft inc. All rights reserved.
+ *
+ * This source code is licensed under Apache 2.0 License,
+ * attached with Common Clause Condition 1.0, found in the LICENSES directory.
+ */
+
+package nebula_go
+
+import (
+	"fmt"
+	"net"
+	"time"
+)
+
+// Resolve the host name to the addresses of all its A and AAAA records with the port of host
+func lookupHostIPs(lookupIP func(host string) ([]net.IP, error), host HostAddress) ([]HostAddress, error) {
+	ips, err := lookupIP(host.Host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s, error: %s", host.Host, err.Error())
+	}
+	hosts := make([]HostAddress, 0, len(ips))
+	for _, ip := range ips {
+		if addr := (HostAddress{Host: ip.String(), Port: host.Port}); !containsHost(hosts, addr) {
+			hosts = append(hosts, addr)
+		}
+	}
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("failed to resolve %s: no address is found", host.Host)
+	}
+	return hosts, nil
+}
+
+// Replace the discovered hosts of the pool, the caller should hold the lock. The states of the removed
+// hosts are dropped and their idle connections are removed from the pool and returned to be closed,
+// their active connections are closed when they are released.
+func (pool *ConnectionPool) setDiscoveredHosts(discovered []HostAddress) (closing []*connection) {
+	addresses := append([]HostAddress(nil), pool.staticAddresses...)
+	var added, removed []HostAddress
+	for _, host := range discovered {
+		if containsHost(addresses, host) {
+			continue
+		}
+		addresses = append(addresses, host)
+		if !containsHost(pool.addresses, host) {
+			added = append(added, host)
+		}
+		// The certificates of the discovered hosts are verified against the DNS name
+		if pool.sslConfig != nil {
+			if pool.serverNames == nil {
+				pool.serverNames = make(map[HostAddress]string)
+			}
+			if name, ok := pool.conf.SSLServerNames[pool.conf.DNSHost.String()]; ok {
+				pool.serverNames[host] = name
+			} else {
+				pool.serverNames[host] = pool.conf.DNSHost.Host
+			}
+		}
+	}
+	for _, host := range pool.addresses {
+		if !containsHost(addresses, host) {
+			removed = append(removed, host)
+			delete(pool.hostStates, host)
+			delete(pool.serverNames, host)
+		}
+	}
+	pool.addresses = addresses
+	for ele := pool.idleConnectionQueue.Front(); ele != nil; {
+		next := ele.Next()
+		if conn := ele.Value.(*connection); containsHost(removed, conn.severAddress) {
+			pool.idleConnectionQueue.Remove(ele)
+			closing = append(closing, conn)
+		}
+		ele = next
+	}
+	if len(added) > 0 || len(removed) > 0 {
+		pool.log.Info("hosts are refreshed", "dns_host", pool.conf.DNSHost.Host, "added", added, "removed", removed)
+	}
+	return closing
+}
+
+// startDiscovery starts hostDiscovery if DNSHost and DNSRefreshInterval are set
+func (pool *ConnectionPool) startDiscovery() {
+	if pool.conf.DNSHost.Host != "" && pool.conf.DNSRefreshInterval > 0 && pool.discoveryChan == nil {
+		pool.discoveryChan = make(chan struct{}, 1)
+		go pool.hostDiscovery()
+	}
+}
+
+func (pool *ConnectionPool) hostDiscovery() {
+	d := pool.conf.DNSRefreshInterval
+	t := time.NewTimer(d)
+
+	for {
+		select {
+		case <-t.C:
+		case <-pool.discoveryChan: // pool was closed.
+		}
+
+		// The name is resolved without the lock, the hosts are kept if it fails to be resolved
+		var discovered []HostAddress
+		var err error
+		pool.rwLock.RLock()
+		closed := pool.closed
+		pool.rwLock.RUnlock()
+		if !closed {
+			discovered, err = lookupHostIPs(pool.lookupIP, pool.conf.DNSHost)
+		}
+
+		pool.rwLock.Lock()
+
+		if pool.closed {
+			pool.discoveryChan = nil
+			pool.rwLock.Unlock()
+			return
+		}
+
+		var closing []*connection
+		if err != nil {
+			pool.log.Warn("failed to refresh the hosts", "dns_host", pool.conf.DNSHost.Host, "error", err)
+		} else {
+			closing = pool.setDiscoveredHosts(discovered)
+		}
+		pool.rwLock.Unlock()
+		for _, c := range closing {
+			c.close()
+		}
+
+		t.Reset(d)
+	}
+}
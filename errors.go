@@ -0,0 +1,122 @@
+/* Copyright (c) 2021 vesoft inc. All rights reserved.
+ *
+ * This source code is licensed under Apache 2.0 License,
+ * attached with Common Clause Condition 1.0, found in the LICENSES directory.
+ */
+
+package nebula_go
+
+import (
+	"errors"
+	"fmt"
+)
+
+// The errors matched by an ExecutionError with errors.Is according to its Code, e.g.
+//
+//	if _, err := session.ExecuteAndCheck(stmt); errors.Is(err, nebula_go.ErrPermissionDenied) {
+//		// respond 403
+//	}
+var (
+	// ErrPermissionDenied is matched by the code ErrorCode_E_BAD_PERMISSION
+	ErrPermissionDenied = errors.New("permission denied")
+	// ErrSpaceNotFound is matched by the code ErrorCode_E_SPACE_NOT_FOUND
+	ErrSpaceNotFound = errors.New("space not found")
+	// ErrSyntaxError is matched by the code ErrorCode_E_SYNTAX_ERROR
+	ErrSyntaxError = errors.New("syntax error")
+)
+
+// TransportError is returned when the transport to the graph service fails to be created or opened
+type TransportError struct {
+	Msg string // the readable message of the error
+	Err error  // the underlying cause, nil if there is none
+}
+
+func (e *TransportError) Error() string {
+	return e.Msg
+}
+
+func (e *TransportError) Unwrap() error {
+	return e.Err
+}
+
+// AuthError is returned when the authentication fails.
+// Code and ServerMsg are the error code and message returned by the server,
+// Code is ErrorCode_E_RPC_FAILURE if the request failed before a response was received.
+type AuthError struct {
+	Msg       string // the readable message of the error
+	Code      ErrorCode
+	ServerMsg string
+	Err       error // the underlying cause, nil if there is none
+}
+
+func (e *AuthError) Error() string {
+	return e.Msg
+}
+
+func (e *AuthError) Unwrap() error {
+	return e.Err
+}
+
+// ExecutionError is returned when the server reports that a query is not succeeded.
+// Code and ServerMsg are the error code and message returned by the server,
+// e.g. to tell a missing space from a syntax error.
+type ExecutionError struct {
+	Msg       string // the readable message of the error
+	Code      ErrorCode
+	ServerMsg string
+}
+
+func (e *ExecutionError) Error() string {
+	return e.Msg
+}
+
+// Is reports whether the error code of the server matches the target, see ErrPermissionDenied
+func (e *ExecutionError) Is(target error) bool {
+	switch target {
+	case ErrPermissionDenied:
+		return e.Code == ErrorCode_E_BAD_PERMISSION
+	case ErrSpaceNotFound:
+		return e.Code == ErrorCode_E_SPACE_NOT_FOUND
+	case ErrSyntaxError:
+		return e.Code == ErrorCode_E_SYNTAX_ERROR
+	}
+	return false
+}
+
+// ConversionError is returned by the accessors of ValueWrapper when the value does not have the
+// requested type or cannot be converted to it, e.g. when AsInt is called on a string
+type ConversionError struct {
+	Msg    string // the readable message of the error
+	Type   string // the type of the value, see ValueWrapper.GetType
+	Target string // the requested type
+}
+
+func (e *ConversionError) Error() string {
+	return e.Msg
+}
+
+// BatchError is returned by Session.ExecuteBatch when some statements failed
+type BatchError struct {
+	// The error of each statement in the batch, nil if the statement succeeded
+	Errors []error
+}
+
+func (e *BatchError) Error() string {
+	failed := e.FailedIndexes()
+	if len(failed) == 0 {
+		return "no statement failed in the batch"
+	}
+	return fmt.Sprintf("failed to execute %d of %d statements, first failed statement index: %d, error: %s",
+		len(failed), len(e.Errors), failed[0], e.Errors[failed[0]].Error())
+}
+
+// FailedIndexes returns the indexes of the failed statements
+func (e *BatchError) FailedIndexes() []int {
+	var failed []int
+	for i, err := range e.Errors {
+		if err != nil {
+			failed = append(failed, i)
+		}
+	}
+	return failed
+}
@@ -0,0 +1,229 @@
+/* Copyright (c) 2021 vesoft inc. All rights reserved.
+ *
+ * This source code is licensed under Apache 2.0 License,
+ * attached with Common Clause Condition 1.0, found in the LICENSES directory.
+ */
+
+// Package fake provides an in-memory graph service returning canned responses,
+// to test code using the client without a running Nebula Graph, e.g.
+//
+//	service := fake.NewGraphService()
+//	service.SetResult("MATCH (v:player) RETURN v.name AS name", []string{"name"},
+//		[]*nebula.Value{{SVal: []byte("Tim")}})
+//	conf := nebula_go.GetDefaultConf()
+//	conf.GraphServiceFactory = func(host nebula_go.HostAddress) (nebula_go.GraphService, error) {
+//		return service, nil
+//	}
+//	pool, err := nebula_go.NewConnectionPool([]nebula_go.HostAddress{{Host: "127.0.0.1", Port: 3699}},
+//		conf, nebula_go.DefaultLogger{})
+package fake
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/vesoft-inc/nebula-go/v2/nebula"
+	"github.com/vesoft-inc/nebula-go/v2/nebula/graph"
+)
+
+// GraphService is an in-memory graph service, it is safe for concurrent use by the connections
+// of a pool. The statements are matched exactly against the canned responses, a statement
+// without a canned response succeeds with an empty result.
+// As by the server, the space of a session is set by its succeeded USE statements and reported
+// in the responses which do not set another space.
+type GraphService struct {
+	mu            sync.Mutex
+	users         map[string]string
+	responses     map[string]*graph.ExecutionResponse
+	sessions      map[int64]string // the space of each session
+	nextSessionID int64
+	executed      []string
+}
+
+// NewGraphService returns a fake graph service accepting any credentials
+func NewGraphService() *GraphService {
+	return &GraphService{
+		responses:     make(map[string]*graph.ExecutionResponse),
+		sessions:      make(map[int64]string),
+		nextSessionID: 1,
+	}
+}
+
+// SetUser adds a user, once a user is added only the added users are authenticated
+func (s *GraphService) SetUser(username, password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.users == nil {
+		s.users = make(map[string]string)
+	}
+	s.users[username] = password
+}
+
+// SetResponse sets the response returned for the statement
+func (s *GraphService) SetResponse(stmt string, resp *graph.ExecutionResponse) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses[stmt] = resp
+}
+
+// SetResult sets a succeeded response with the columns and rows returned for the statement
+func (s *GraphService) SetResult(stmt string, columns []string, rows ...[]*nebula.Value) {
+	data := &nebula.DataSet{
+		ColumnNames: make([][]byte, 0, len(columns)),
+		Rows:        make([]*nebula.Row, 0, len(rows)),
+	}
+	for _, column := range columns {
+		data.ColumnNames = append(data.ColumnNames, []byte(column))
+	}
+	for _, row := range rows {
+		data.Rows = append(data.Rows, &nebula.Row{Values: row})
+	}
+	s.SetResponse(stmt, &graph.ExecutionResponse{ErrorCode: nebula.ErrorCode_SUCCEEDED, Data: data})
+}
+
+// SetError sets a failed response with the error code and message returned for the statement
+func (s *GraphService) SetError(stmt string, code nebula.ErrorCode, msg string) {
+	s.SetResponse(stmt, &graph.ExecutionResponse{ErrorCode: code, ErrorMsg: []byte(msg)})
+}
+
+// Executed returns the statements executed in the valid sessions, in order
+func (s *GraphService) Executed() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.executed...)
+}
+
+// Sessions returns the number of sessions which are not signed out
+func (s *GraphService) Sessions() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.sessions)
+}
+
+// Authenticate creates a session if the credentials are accepted
+func (s *GraphService) Authenticate(username []byte, password []byte) (*graph.AuthResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.users != nil {
+		if expected, ok := s.users[string(username)]; !ok || expected != string(password) {
+			return &graph.AuthResponse{
+				ErrorCode: nebula.ErrorCode_E_BAD_USERNAME_PASSWORD,
+				ErrorMsg:  []byte("Invalid username or password"),
+			}, nil
+		}
+	}
+	sessionID := s.nextSessionID
+	s.nextSessionID++
+	s.sessions[sessionID] = ""
+	return &graph.AuthResponse{ErrorCode: nebula.ErrorCode_SUCCEEDED, SessionID: &sessionID}, nil
+}
+
+// Signout removes the session
+func (s *GraphService) Signout(sessionId int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, sessionId)
+	return nil
+}
+
+// Execute returns the canned response of the statement, or an empty succeeded response.
+// A failed response is returned if the session does not exist.
+func (s *GraphService) Execute(sessionId int64, stmt []byte) (*graph.ExecutionResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	space, ok := s.sessions[sessionId]
+	if !ok {
+		return &graph.ExecutionResponse{
+			ErrorCode: nebula.ErrorCode_E_SESSION_INVALID,
+			ErrorMsg:  []byte("Session not existed"),
+		}, nil
+	}
+	s.executed = append(s.executed, string(stmt))
+	resp := graph.ExecutionResponse{ErrorCode: nebula.ErrorCode_SUCCEEDED}
+	if canned, ok := s.responses[string(stmt)]; ok {
+		resp = *canned
+	}
+	if resp.ErrorCode != nebula.ErrorCode_SUCCEEDED {
+		return &resp, nil
+	}
+	if fields := strings.Fields(string(stmt)); len(fields) == 2 && strings.EqualFold(fields[0], "USE") {
+		space = strings.Trim(strings.TrimSuffix(fields[1], ";"), "`")
+		s.sessions[sessionId] = space
+	}
+	if resp.SpaceName == nil && space != "" {
+		resp.SpaceName = []byte(space)
+	}
+	return &resp, nil
+}
+
+// ExecuteJson returns the response of Execute in the JSON format of the server:
+//
+//	{"errors": [{"code": 0, "message": ""}],
+//	 "results": [{"spaceName": "", "latencyInUs": 0, "columns": ["col1"], "data": [{"row": [value]}]}]}
+//
+// The results are left out if the query failed. Null, bools, numbers and strings are encoded as
+// such, the other values are encoded as their string representation.
+func (s *GraphService) ExecuteJson(sessionId int64, stmt []byte) ([]byte, error) {
+	resp, err := s.Execute(sessionId, stmt)
+	if err != nil {
+		return nil, err
+	}
+	type jsonError struct {
+		Code    nebula.ErrorCode `json:"code"`
+		Message string           `json:"message,omitempty"`
+	}
+	type jsonRow struct {
+		Row []interface{} `json:"row"`
+	}
+	type jsonResult struct {
+		SpaceName   string    `json:"spaceName"`
+		LatencyInUs int32     `json:"latencyInUs"`
+		Columns     []string  `json:"columns"`
+		Data        []jsonRow `json:"data"`
+	}
+	out := struct {
+		Errors  []jsonError  `json:"errors"`
+		Results []jsonResult `json:"results,omitempty"`
+	}{Errors: []jsonError{{Code: resp.ErrorCode, Message: string(resp.ErrorMsg)}}}
+	if resp.ErrorCode == nebula.ErrorCode_SUCCEEDED {
+		result := jsonResult{SpaceName: string(resp.SpaceName), LatencyInUs: resp.LatencyInUs,
+			Columns: []string{}, Data: []jsonRow{}}
+		if resp.Data != nil {
+			for _, column := range resp.Data.ColumnNames {
+				result.Columns = append(result.Columns, string(column))
+			}
+			for _, row := range resp.Data.Rows {
+				values := make([]interface{}, 0, len(row.Values))
+				for _, value := range row.Values {
+					values = append(values, jsonValue(value))
+				}
+				result.Data = append(result.Data, jsonRow{Row: values})
+			}
+		}
+		out.Results = []jsonResult{result}
+	}
+	return json.Marshal(out)
+}
+
+// Convert a value into the Go value encoded in the JSON response
+func jsonValue(value *nebula.Value) interface{} {
+	switch {
+	case value.IsSetNVal():
+		return nil
+	case value.IsSetBVal():
+		return value.GetBVal()
+	case value.IsSetIVal():
+		return value.GetIVal()
+	case value.IsSetFVal():
+		return value.GetFVal()
+	case value.IsSetSVal():
+		return string(value.GetSVal())
+	}
+	return value.String()
+}
+
+// Close does nothing, the service is shared by the connections and kept after they are closed
+func (s *GraphService) Close() error {
+	return nil
+}
@@ -0,0 +1,173 @@
+/* Copyright (c) 2021 vesoft inc. All rights reserved.
+ *
+ * This source code is licensed under Apache 2.0 License,
+ * attached with Common Clause Condition 1.0, found in the LICENSES directory.
+ */
+
+package nebula_go
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/vesoft-inc/nebula-go/v2/nebula"
+)
+
+// SafeFormat builds a statement from the template by replacing its placeholders with the literals
+// of the arguments, as a safe replacement of fmt.Sprintf. The placeholders are either positional,
+// ? replaced by the arguments in order, or named, @name replaced by the value of the name in the single
+// map[string]interface{} argument, e.g.
+//
+//	SafeFormat("FETCH PROP ON player ?", vid)
+//	SafeFormat("GO FROM @vid OVER follow WHERE $$.player.age > @age", map[string]interface{}{"vid": vid, "age": 30})
+//
+// The server of this client version does not accept parameters, so the values are sent as literals
+// rather than as parameters. An argument can never change the structure of the statement:
+//   - strings are quoted with double quotes, with the backslashes, quotes and line breaks escaped,
+//   - numbers and booleans are formatted as such, NaN and infinite floats are rejected,
+//   - nil is NULL, time.Time, nebula.Date, nebula.Time and nebula.DateTime are the date and time functions,
+//   - slices and maps with string keys are lists and maps of the literals of their elements.
+//
+// The arguments have the types supported by BuildParams, and are values only: the names of spaces,
+// schemas and properties cannot be passed as arguments. The placeholders in quoted strings, identifiers
+// quoted by backticks and comments are left as is. An error is returned if the placeholders and the
+// arguments do not match, or if an argument has an unsupported type.
+func SafeFormat(template string, args ...interface{}) (string, error) {
+	var named map[string]interface{}
+	positional, namedUsed := 0, false
+	var b strings.Builder
+	for i := 0; i < len(template); i++ {
+		c := template[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			end := i + 1
+			for ; end < len(template) && template[end] != c; end++ {
+				if template[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(template) {
+				end = len(template) - 1
+			}
+			b.WriteString(template[i : end+1])
+			i = end
+		case c == '#' || strings.HasPrefix(template[i:], "//") || strings.HasPrefix(template[i:], "/*"):
+			// Copy the comment up to its end or the end of the template
+			start, terminator := i+1, "\n"
+			if strings.HasPrefix(template[i:], "/*") {
+				start, terminator = i+2, "*/"
+			}
+			end := len(template)
+			if j := strings.Index(template[start:], terminator); j >= 0 {
+				end = start + j + len(terminator)
+			}
+			b.WriteString(template[i:end])
+			i = end - 1
+		case c == '?':
+			if namedUsed {
+				return "", fmt.Errorf("failed to format statement: positional and named placeholders are mixed")
+			}
+			if positional >= len(args) {
+				return "", fmt.Errorf("failed to format statement: missing argument for placeholder %d", positional+1)
+			}
+			literal, err := formatLiteral(args[positional])
+			if err != nil {
+				return "", fmt.Errorf("failed to format statement: argument %d: %s", positional+1, err.Error())
+			}
+			b.WriteString(literal)
+			positional++
+		case c == '@' && i+1 < len(template) && isNameStart(template[i+1]):
+			end := i + 1
+			for end < len(template) && isIdentRune(rune(template[end])) && template[end] != '$' {
+				end++
+			}
+			name := template[i+1 : end]
+			if positional > 0 {
+				return "", fmt.Errorf("failed to format statement: positional and named placeholders are mixed")
+			}
+			if !namedUsed {
+				var ok bool
+				if len(args) == 1 {
+					named, ok = args[0].(map[string]interface{})
+				}
+				if !ok {
+					return "", fmt.Errorf("failed to format statement: named placeholders require a single map[string]interface{} argument")
+				}
+				namedUsed = true
+			}
+			v, ok := named[name]
+			if !ok {
+				return "", fmt.Errorf("failed to format statement: missing argument for placeholder @%s", name)
+			}
+			literal, err := formatLiteral(v)
+			if err != nil {
+				return "", fmt.Errorf("failed to format statement: argument %s: %s", name, err.Error())
+			}
+			b.WriteString(literal)
+			i = end - 1
+		default:
+			b.WriteByte(c)
+		}
+	}
+	if !namedUsed && positional != len(args) {
+		return "", fmt.Errorf("failed to format statement: %d arguments for %d placeholders", len(args), positional)
+	}
+	return b.String(), nil
+}
+
+// Check if the byte can start the name of a named placeholder
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// Format a Go value as an nGQL literal as ngqlLiteral, including the lists and the maps
+func formatLiteral(v interface{}) (string, error) {
+	switch v.(type) {
+	case nebula.Date, *nebula.Date, nebula.Time, *nebula.Time, nebula.DateTime, *nebula.DateTime:
+		return ngqlLiteral(v)
+	}
+	value, err := toNebulaValue(v)
+	if err != nil {
+		return "", err
+	}
+	return collectionLiteral(value)
+}
+
+// Format a nebula value as valueLiteral, the elements of lists and maps are formatted recursively
+func collectionLiteral(value *nebula.Value) (string, error) {
+	switch {
+	case value.IsSetLVal():
+		elems := make([]string, 0, len(value.GetLVal().GetValues()))
+		for _, elem := range value.GetLVal().GetValues() {
+			literal, err := collectionLiteral(elem)
+			if err != nil {
+				return "", err
+			}
+			elems = append(elems, literal)
+		}
+		return "[" + strings.Join(elems, ", ") + "]", nil
+	case value.IsSetMVal():
+		kvs := value.GetMVal().GetKvs()
+		keys := make([]string, 0, len(kvs))
+		for k := range kvs {
+			keys = append(keys, k)
+		}
+		// Sort the keys so the same map always gives the same statement
+		sort.Strings(keys)
+		elems := make([]string, 0, len(keys))
+		for _, k := range keys {
+			if k == "" || strings.ContainsAny(k, "`\n") {
+				return "", fmt.Errorf("map key %q is empty or contains a backtick or a line break", k)
+			}
+			literal, err := collectionLiteral(kvs[k])
+			if err != nil {
+				return "", err
+			}
+			elems = append(elems, "`"+k+"`: "+literal)
+		}
+		return "{" + strings.Join(elems, ", ") + "}", nil
+	default:
+		return valueLiteral(value)
+	}
+}
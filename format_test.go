@@ -0,0 +1,63 @@
+/* Copyright (c) 2021 vesoft inc. All rights reserved.
+ *
+ * This source code is licensed under Apache 2.0 License,
+ * attached with Common Clause Condition 1.0, found in the LICENSES directory.
+ */
+
+package nebula_go
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vesoft-inc/nebula-go/v2/nebula"
+)
+
+func TestSafeFormat(t *testing.T) {
+	stmt, err := SafeFormat("FETCH PROP ON player ?", `p1" OR 1==1 /*`)
+	assert.Nil(t, err)
+	assert.Equal(t, `FETCH PROP ON player "p1\" OR 1==1 /*"`, stmt)
+
+	stmt, err = SafeFormat("INSERT VERTEX t(a, b, c, d, e, f) VALUES ?:(?, ?, ?, ?, ?, ?)",
+		int64(1), "x\\\ny", 2.0, true, nil, time.Date(2021, 3, 4, 5, 6, 7, 8000, time.UTC), nebula.Date{Year: 2021, Month: 3, Day: 4})
+	assert.Nil(t, err)
+	assert.Equal(t, `INSERT VERTEX t(a, b, c, d, e, f) VALUES 1:("x\\\ny", 2.0, true, NULL, `+
+		`datetime("2021-03-04T05:06:07.000008"), date("2021-03-04"))`, stmt)
+
+	stmt, err = SafeFormat("YIELD ? AS l, ? AS m", []interface{}{1, "a"}, map[string]interface{}{"b": 2, "a": []int{3}})
+	assert.Nil(t, err)
+	assert.Equal(t, "YIELD [1, \"a\"] AS l, {`a`: [3], `b`: 2} AS m", stmt)
+
+	// Named placeholders, the quoted strings, backticks, comments and variables are kept
+	stmt, err = SafeFormat("GO FROM @vid OVER follow WHERE $$.player.age > @age AND $$.player.name != \"@vid ?\" "+
+		"YIELD follow._dst AS `@id` | YIELD $-.`@id` # @age ?\n/* ? */",
+		map[string]interface{}{"vid": "p1", "age": 30, "unused": 1})
+	assert.Nil(t, err)
+	assert.Equal(t, "GO FROM \"p1\" OVER follow WHERE $$.player.age > 30 AND $$.player.name != \"@vid ?\" "+
+		"YIELD follow._dst AS `@id` | YIELD $-.`@id` # @age ?\n/* ? */", stmt)
+	stmt, err = SafeFormat(`FETCH PROP ON follow "a"->"b"@0`)
+	assert.Nil(t, err)
+	assert.Equal(t, `FETCH PROP ON follow "a"->"b"@0`, stmt)
+
+	invalid := []struct {
+		template string
+		args     []interface{}
+	}{
+		{"YIELD ?, ?", []interface{}{1}},
+		{"YIELD ?", []interface{}{1, 2}},
+		{"YIELD 1", []interface{}{1}},
+		{"YIELD ?", []interface{}{math.NaN()}},
+		{"YIELD ?", []interface{}{struct{}{}}},
+		{"YIELD ?", []interface{}{map[string]interface{}{"a`": 1}}},
+		{"YIELD @a", []interface{}{map[string]interface{}{"b": 1}}},
+		{"YIELD @a", []interface{}{1}},
+		{"YIELD @a, ?", []interface{}{map[string]interface{}{"a": 1}}},
+		{"YIELD ?, @a", []interface{}{map[string]interface{}{"a": 1}}},
+	}
+	for _, c := range invalid {
+		_, err = SafeFormat(c.template, c.args...)
+		assert.NotNil(t, err, c.template)
+	}
+}
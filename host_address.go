@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"strconv"
 )
 
 type HostAddress struct {
@@ -17,6 +18,11 @@ type HostAddress struct {
 	Port int
 }
 
+// String returns the address in form host:port
+func (h HostAddress) String() string {
+	return net.JoinHostPort(h.Host, strconv.Itoa(h.Port))
+}
+
 func DomainToIP(addresses []HostAddress) ([]HostAddress, error) {
 	var newHostsList []HostAddress
 	for _, host := range addresses {
@@ -0,0 +1,344 @@
+/* Copyright (c) 2021 vesoft inc. All rights reserved.
+ *
+ * This source code is licensed under Apache 2.0 License,
+ * attached with Common Clause Condition 1.0, found in the LICENSES directory.
+ */
+
+package nebula_go
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/vesoft-inc/nebula-go/v2/nebula"
+)
+
+// VertexInserter builds a batched INSERT VERTEX statement of a tag, e.g.
+//
+//	NewVertexInserter("player").Prop("name", "Tim").Prop("age", 42).VID("p1").
+//		Prop("name", "Tony").Prop("age", 36).VID("p2")
+//
+// The properties set by Prop are added to the row of the next vertex ID passed to VID,
+// all rows must set the same properties. The values are converted into nGQL literals
+// as described by NewVertexInserter.
+type VertexInserter struct {
+	rows insertRows
+}
+
+// NewVertexInserter returns a VertexInserter for the tag.
+// Supported values are nil, bool, integers, floats, string, []byte, time.Time, *nebula.Value,
+// and nebula.Date, nebula.Time and nebula.DateTime or their pointers. Strings are quoted and escaped,
+// a time.Time is converted into a datetime in UTC and nil is converted into NULL.
+// The vertex IDs must be strings or integers.
+func NewVertexInserter(tag string) *VertexInserter {
+	return &VertexInserter{rows: insertRows{schema: tag}}
+}
+
+// Prop sets a property of the next row
+func (ins *VertexInserter) Prop(name string, value interface{}) *VertexInserter {
+	ins.rows.prop(name, value)
+	return ins
+}
+
+// VID adds the row of the vertex with the properties set since the previous row
+func (ins *VertexInserter) VID(vid interface{}) *VertexInserter {
+	key, err := vidLiteral(vid)
+	ins.rows.add(key, err)
+	return ins
+}
+
+// Len returns the number of rows
+func (ins *VertexInserter) Len() int {
+	return len(ins.rows.rows)
+}
+
+// Reset removes the rows and the error, so that the inserter can build the next batch
+func (ins *VertexInserter) Reset() {
+	ins.rows.reset()
+}
+
+// Statement returns the INSERT VERTEX statement of the rows.
+// An error is returned if a value or vertex ID is not supported, the rows set different
+// properties or there is no row.
+func (ins *VertexInserter) Statement() (string, error) {
+	return ins.rows.statement("VERTEX")
+}
+
+// Execute executes the statement on the session, see Session.ExecuteAndCheck
+func (ins *VertexInserter) Execute(session *Session) (*ResultSet, error) {
+	stmt, err := ins.Statement()
+	if err != nil {
+		return nil, err
+	}
+	return session.ExecuteAndCheck(stmt)
+}
+
+// EdgeInserter builds a batched INSERT EDGE statement of an edge type, e.g.
+//
+//	NewEdgeInserter("follow").Prop("degree", 95).Edge("p1", "p2").
+//		Prop("degree", 90).EdgeRank("p1", "p3", 1)
+//
+// It is used as VertexInserter.
+type EdgeInserter struct {
+	rows insertRows
+}
+
+// NewEdgeInserter returns an EdgeInserter for the edge type, the values are converted
+// as by NewVertexInserter
+func NewEdgeInserter(edgeType string) *EdgeInserter {
+	return &EdgeInserter{rows: insertRows{schema: edgeType}}
+}
+
+// Prop sets a property of the next row
+func (ins *EdgeInserter) Prop(name string, value interface{}) *EdgeInserter {
+	ins.rows.prop(name, value)
+	return ins
+}
+
+// Edge adds the row of the edge from src to dst with rank 0,
+// with the properties set since the previous row
+func (ins *EdgeInserter) Edge(src, dst interface{}) *EdgeInserter {
+	return ins.EdgeRank(src, dst, 0)
+}
+
+// EdgeRank adds the row of the edge from src to dst with the rank,
+// with the properties set since the previous row
+func (ins *EdgeInserter) EdgeRank(src, dst interface{}, rank int64) *EdgeInserter {
+	srcKey, err := vidLiteral(src)
+	if err != nil {
+		ins.rows.add("", err)
+		return ins
+	}
+	dstKey, err := vidLiteral(dst)
+	ins.rows.add(fmt.Sprintf("%s->%s@%d", srcKey, dstKey, rank), err)
+	return ins
+}
+
+// Len returns the number of rows
+func (ins *EdgeInserter) Len() int {
+	return len(ins.rows.rows)
+}
+
+// Reset removes the rows and the error, so that the inserter can build the next batch
+func (ins *EdgeInserter) Reset() {
+	ins.rows.reset()
+}
+
+// Statement returns the INSERT EDGE statement of the rows, see VertexInserter.Statement
+func (ins *EdgeInserter) Statement() (string, error) {
+	return ins.rows.statement("EDGE")
+}
+
+// Execute executes the statement on the session, see Session.ExecuteAndCheck
+func (ins *EdgeInserter) Execute(session *Session) (*ResultSet, error) {
+	stmt, err := ins.Statement()
+	if err != nil {
+		return nil, err
+	}
+	return session.ExecuteAndCheck(stmt)
+}
+
+// The rows of an insert statement, the first error is kept and returned by statement
+type insertRows struct {
+	schema string
+	// The property names of the first row, in order
+	names []string
+	// The properties of the next row
+	pending map[string]string
+	order   []string
+	// The rows formatted as key:(values)
+	rows []string
+	err  error
+}
+
+func (r *insertRows) prop(name string, value interface{}) {
+	if r.err != nil {
+		return
+	}
+	literal, err := ngqlLiteral(value)
+	if err != nil {
+		r.err = fmt.Errorf("failed to build insert statement: property %s of row %d: %s", name, len(r.rows), err.Error())
+		return
+	}
+	if r.pending == nil {
+		r.pending = make(map[string]string)
+	}
+	if _, ok := r.pending[name]; !ok {
+		r.order = append(r.order, name)
+	}
+	r.pending[name] = literal
+}
+
+func (r *insertRows) add(key string, err error) {
+	if r.err != nil {
+		return
+	}
+	if err != nil {
+		r.err = fmt.Errorf("failed to build insert statement: row %d: %s", len(r.rows), err.Error())
+		return
+	}
+	if len(r.rows) == 0 {
+		r.names = r.order
+	} else if len(r.order) != len(r.names) {
+		r.err = fmt.Errorf("failed to build insert statement: row %d sets %d properties, but the first row sets %d",
+			len(r.rows), len(r.order), len(r.names))
+		return
+	}
+	values := make([]string, 0, len(r.names))
+	for _, name := range r.names {
+		literal, ok := r.pending[name]
+		if !ok {
+			r.err = fmt.Errorf("failed to build insert statement: row %d does not set property %s", len(r.rows), name)
+			return
+		}
+		values = append(values, literal)
+	}
+	r.rows = append(r.rows, fmt.Sprintf("%s:(%s)", key, strings.Join(values, ", ")))
+	r.pending = nil
+	r.order = nil
+}
+
+func (r *insertRows) reset() {
+	*r = insertRows{schema: r.schema}
+}
+
+func (r *insertRows) statement(kind string) (string, error) {
+	if r.err != nil {
+		return "", r.err
+	}
+	if len(r.rows) == 0 {
+		return "", fmt.Errorf("failed to build insert statement: no row is added")
+	}
+	if len(r.order) > 0 {
+		return "", fmt.Errorf("failed to build insert statement: properties are set after the last row")
+	}
+	schema, err := quoteIdentifier(r.schema)
+	if err != nil {
+		return "", err
+	}
+	names := make([]string, 0, len(r.names))
+	for _, name := range r.names {
+		quoted, err := quoteIdentifier(name)
+		if err != nil {
+			return "", err
+		}
+		names = append(names, quoted)
+	}
+	return fmt.Sprintf("INSERT %s %s(%s) VALUES %s",
+		kind, schema, strings.Join(names, ", "), strings.Join(r.rows, ", ")), nil
+}
+
+// Quote the name of a schema or property with backticks
+func quoteIdentifier(name string) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("failed to build insert statement: the name is empty")
+	}
+	if strings.ContainsAny(name, "`\n") {
+		return "", fmt.Errorf("failed to build insert statement: the name %q contains a backtick or a line break", name)
+	}
+	return "`" + name + "`", nil
+}
+
+// Format a vertex ID, which must be a string or an integer
+func vidLiteral(vid interface{}) (string, error) {
+	value, err := toNebulaValue(vid)
+	if err != nil {
+		return "", fmt.Errorf("vertex ID: %s", err.Error())
+	}
+	if !value.IsSetSVal() && !value.IsSetIVal() {
+		return "", fmt.Errorf("vertex ID: unsupported type %T, must be a string or an integer", vid)
+	}
+	return valueLiteral(value)
+}
+
+// Format a Go value as an nGQL literal
+func ngqlLiteral(v interface{}) (string, error) {
+	value := nebula.NewValue()
+	switch val := v.(type) {
+	case nebula.Date:
+		value.DVal = &val
+	case *nebula.Date:
+		value.DVal = val
+	case nebula.Time:
+		value.TVal = &val
+	case *nebula.Time:
+		value.TVal = val
+	case nebula.DateTime:
+		value.DtVal = &val
+	case *nebula.DateTime:
+		value.DtVal = val
+	default:
+		var err error
+		if value, err = toNebulaValue(v); err != nil {
+			return "", err
+		}
+	}
+	if value.CountSetFieldsValue() == 0 {
+		// A nil pointer of a date or time
+		return "NULL", nil
+	}
+	return valueLiteral(value)
+}
+
+// Format a nebula value as an nGQL literal
+func valueLiteral(value *nebula.Value) (string, error) {
+	switch {
+	case value.IsSetNVal():
+		return "NULL", nil
+	case value.IsSetBVal():
+		return strconv.FormatBool(value.GetBVal()), nil
+	case value.IsSetIVal():
+		return strconv.FormatInt(value.GetIVal(), 10), nil
+	case value.IsSetFVal():
+		f := value.GetFVal()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return "", fmt.Errorf("float %v has no literal", f)
+		}
+		s := strconv.FormatFloat(f, 'f', -1, 64)
+		if !strings.Contains(s, ".") {
+			// Keep the literal a float, otherwise it is parsed as an integer
+			s += ".0"
+		}
+		return s, nil
+	case value.IsSetSVal():
+		return quoteString(value.GetSVal()), nil
+	case value.IsSetDVal():
+		d := value.GetDVal()
+		return fmt.Sprintf("date(\"%04d-%02d-%02d\")", d.Year, d.Month, d.Day), nil
+	case value.IsSetTVal():
+		t := value.GetTVal()
+		return fmt.Sprintf("time(\"%02d:%02d:%02d.%06d\")", t.Hour, t.Minute, t.Sec, t.Microsec), nil
+	case value.IsSetDtVal():
+		dt := value.GetDtVal()
+		return fmt.Sprintf("datetime(\"%04d-%02d-%02dT%02d:%02d:%02d.%06d\")",
+			dt.Year, dt.Month, dt.Day, dt.Hour, dt.Minute, dt.Sec, dt.Microsec), nil
+	default:
+		return "", fmt.Errorf("unsupported property value %s", value.String())
+	}
+}
+
+// Quote a string with double quotes, escaping the backslashes, quotes and control characters
+func quoteString(s []byte) string {
+	var b strings.Builder
+	b.Grow(len(s) + 2)
+	b.WriteByte('"')
+	for _, c := range s {
+		switch c {
+		case '\\', '"':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
@@ -0,0 +1,75 @@
+/* Copyright (c) 2021 vesoft inc. All rights reserved.
+ *
+ * This source code is licensed under Apache 2.0 License,
+ * attached with Common Clause Condition 1.0, found in the LICENSES directory.
+ */
+
+package nebula_go
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vesoft-inc/nebula-go/v2/nebula"
+)
+
+func TestVertexInserter(t *testing.T) {
+	ins := NewVertexInserter("player").
+		Prop("name", "Tim \"The Big\" \\ Duncan\n").Prop("age", 42).VID("p1").
+		Prop("age", uint8(36)).Prop("name", nil).VID(int64(2))
+	assert.Equal(t, 2, ins.Len())
+	stmt, err := ins.Statement()
+	assert.Nil(t, err)
+	assert.Equal(t,
+		"INSERT VERTEX `player`(`name`, `age`) VALUES \"p1\":(\"Tim \\\"The Big\\\" \\\\ Duncan\\n\", 42), 2:(NULL, 36)",
+		stmt)
+
+	ins.Reset()
+	assert.Equal(t, 0, ins.Len())
+	stmt, err = ins.Prop("birthday", nebula.Date{Year: 1976, Month: 4, Day: 25}).
+		Prop("joined", time.Date(1997, 6, 25, 20, 30, 0, 5000, time.FixedZone("UTC-5", -5*3600))).
+		Prop("weight", 113.0).
+		Prop("wake", &nebula.Time{Hour: 7, Minute: 5}).
+		Prop("retired", true).
+		VID("p1").Statement()
+	assert.Nil(t, err)
+	assert.Equal(t, "INSERT VERTEX `player`(`birthday`, `joined`, `weight`, `wake`, `retired`) VALUES "+
+		"\"p1\":(date(\"1976-04-25\"), datetime(\"1997-06-26T01:30:00.000005\"), 113.0, time(\"07:05:00.000000\"), true)",
+		stmt)
+
+	// Tag without properties
+	stmt, err = NewVertexInserter("team").VID("t1").VID("t2").Statement()
+	assert.Nil(t, err)
+	assert.Equal(t, "INSERT VERTEX `team`() VALUES \"t1\":(), \"t2\":()", stmt)
+
+	invalid := []*VertexInserter{
+		NewVertexInserter("player"),
+		NewVertexInserter("player").Prop("age", 1).VID("p1").Prop("age", 2),
+		NewVertexInserter("player").Prop("age", 1).VID("p1").Prop("name", "x").VID("p2"),
+		NewVertexInserter("player").Prop("age", 1).VID("p1").VID("p2"),
+		NewVertexInserter("player").Prop("age", math.NaN()).VID("p1"),
+		NewVertexInserter("player").Prop("tags", []string{"a"}).VID("p1"),
+		NewVertexInserter("player").Prop("age", struct{}{}).VID("p1"),
+		NewVertexInserter("player").Prop("age", 1).VID(1.5),
+		NewVertexInserter("play`er").Prop("age", 1).VID("p1"),
+		NewVertexInserter("player").Prop("", 1).VID("p1"),
+	}
+	for i, ins := range invalid {
+		_, err := ins.Statement()
+		assert.NotNil(t, err, "inserter %d", i)
+	}
+}
+
+func TestEdgeInserter(t *testing.T) {
+	ins := NewEdgeInserter("follow").
+		Prop("degree", 95).Edge("p1", "p2").
+		Prop("degree", 90).EdgeRank(int64(1), int64(3), 2)
+	stmt, err := ins.Statement()
+	assert.Nil(t, err)
+	assert.Equal(t, "INSERT EDGE `follow`(`degree`) VALUES \"p1\"->\"p2\"@0:(95), 1->3@2:(90)", stmt)
+
+	_, err = NewEdgeInserter("follow").Prop("degree", 95).Edge(nil, "p2").Statement()
+	assert.NotNil(t, err)
+}
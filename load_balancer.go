@@ -0,0 +1,91 @@
+/* Copyright (c) 2021 vesoft inc. All rights reserved.
+ *
+ * This source code is licensed under Apache 2.0 License,
+ * attached with Common Clause Condition 1.0, found in the LICENSES directory.
+ */
+
+package nebula_go
+
+import (
+	"hash/fnv"
+	"sync/atomic"
+)
+
+// HostLoad is the load of a host considered by a LoadBalancer
+type HostLoad struct {
+	Address HostAddress
+	// Number of connections to the host in use by sessions
+	ActiveConns int
+	// Number of connections to the host in the pool, including the idle ones
+	TotalConns int
+}
+
+// LoadBalancer selects the host to open a new connection to.
+// Select is called with the pool locked and returns the index of the selected host in hosts,
+// hosts contains the hosts of the pool which are not in backoff and is never empty.
+type LoadBalancer interface {
+	Select(hosts []HostLoad) int
+}
+
+// RoundRobin selects the hosts in turn, it is the default LoadBalancer
+type RoundRobin struct {
+	next uint64
+}
+
+func (rr *RoundRobin) Select(hosts []HostLoad) int {
+	return int((atomic.AddUint64(&rr.next, 1) - 1) % uint64(len(hosts)))
+}
+
+// LeastConnections selects the host with the least active connections,
+// the host with the least connections in the pool is selected on a tie
+type LeastConnections struct{}
+
+func (LeastConnections) Select(hosts []HostLoad) int {
+	selected := 0
+	for i, host := range hosts {
+		min := hosts[selected]
+		if host.ActiveConns < min.ActiveConns ||
+			(host.ActiveConns == min.ActiveConns && host.TotalConns < min.TotalConns) {
+			selected = i
+		}
+	}
+	return selected
+}
+
+// AffinityHasher selects the host of a session by its affinity key, see GetSessionWithAffinity.
+// Select is called with the pool locked and returns the index of the selected host in hosts,
+// hosts contains the hosts of the pool which are available in the order of the addresses and is never empty.
+// The same host should be selected for a key as long as it is available.
+type AffinityHasher interface {
+	Select(key string, hosts []HostLoad) int
+}
+
+// RendezvousHash selects the host with the highest hash of the key and the host, it is the default
+// AffinityHasher. A key keeps its host as long as the host is available, the keys of an unavailable
+// host are spread over the remaining hosts and return to it once it is available again.
+type RendezvousHash struct{}
+
+func (RendezvousHash) Select(key string, hosts []HostLoad) int {
+	selected := 0
+	var max uint64
+	for i, host := range hosts {
+		h := fnv.New64a()
+		h.Write([]byte(host.Address.String()))
+		h.Write([]byte{0})
+		h.Write([]byte(key))
+		if score := mix64(h.Sum64()); i == 0 || score > max {
+			selected, max = i, score
+		}
+	}
+	return selected
+}
+
+// Spread the bits of a FNV hash, whose high bits barely depend on the last bytes hashed
+func mix64(x uint64) uint64 {
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
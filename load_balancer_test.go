@@ -0,0 +1,86 @@
+/* Copyright (c) 2021 vesoft inc. All rights reserved.
+ *
+ * This source code is licensed under Apache 2.0 License,
+ * attached with Common Clause Condition 1.0, found in the LICENSES directory.
+ */
+
+package nebula_go
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoundRobin(t *testing.T) {
+	hosts := []HostLoad{
+		{Address: HostAddress{"127.0.0.1", 3699}},
+		{Address: HostAddress{"127.0.0.1", 3700}},
+		{Address: HostAddress{"127.0.0.1", 3701}},
+	}
+	rr := &RoundRobin{}
+	for i := 0; i < 6; i++ {
+		assert.Equal(t, i%3, rr.Select(hosts))
+	}
+}
+
+func TestLeastConnections(t *testing.T) {
+	hosts := []HostLoad{
+		{Address: HostAddress{"127.0.0.1", 3699}, ActiveConns: 2, TotalConns: 2},
+		{Address: HostAddress{"127.0.0.1", 3700}, ActiveConns: 1, TotalConns: 3},
+		{Address: HostAddress{"127.0.0.1", 3701}, ActiveConns: 1, TotalConns: 1},
+	}
+	assert.Equal(t, 2, LeastConnections{}.Select(hosts))
+
+	hosts[0].ActiveConns = 0
+	assert.Equal(t, 0, LeastConnections{}.Select(hosts))
+}
+
+func TestGetHostExclude(t *testing.T) {
+	hosts := []HostAddress{{"127.0.0.1", 3699}, {"127.0.0.1", 3700}}
+	pool := &ConnectionPool{
+		addresses:  hosts,
+		conf:       PoolConfig{LoadBalancer: &RoundRobin{}},
+		hostStates: make(map[HostAddress]*hostState),
+	}
+	pool.idleConnectionQueue.Init()
+	pool.activeConnectionQueue.Init()
+	for i := 0; i < 4; i++ {
+		host, err := pool.getHost(hosts[0])
+		assert.Nil(t, err)
+		assert.Equal(t, hosts[1], host)
+	}
+
+	_, err := pool.getHost(hosts...)
+	assert.NotNil(t, err)
+}
+
+func TestRendezvousHash(t *testing.T) {
+	hosts := []HostLoad{
+		{Address: HostAddress{"127.0.0.1", 3699}},
+		{Address: HostAddress{"127.0.0.1", 3700}},
+		{Address: HostAddress{"127.0.0.1", 3701}},
+	}
+	selected := make(map[string]HostAddress)
+	used := make(map[HostAddress]bool)
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("key%d", i)
+		host := hosts[RendezvousHash{}.Select(key, hosts)].Address
+		assert.Equal(t, host, hosts[RendezvousHash{}.Select(key, hosts)].Address)
+		selected[key] = host
+		used[host] = true
+	}
+	assert.Len(t, used, 3)
+
+	// Only the keys of the unavailable host are moved
+	available := []HostLoad{hosts[0], hosts[2]}
+	for key, host := range selected {
+		rehashed := available[RendezvousHash{}.Select(key, available)].Address
+		if host != hosts[1].Address {
+			assert.Equal(t, host, rehashed, key)
+		} else {
+			assert.NotEqual(t, host, rehashed, key)
+		}
+	}
+}
@@ -7,9 +7,78 @@
 package nebula_go
 
 import (
+	"fmt"
 	"log"
+	"strconv"
+	"strings"
 )
 
+// StructuredLogger receives the events of the connection pool and its sessions with their details
+// as key-value pairs, e.g. Warn("host is marked down", "host", host, "failures", 3).
+// Failures are logged as warnings if they are recovered from and as errors otherwise,
+// the life cycle of the connections and the sessions is logged as debug.
+// It is set by PoolConfig.Logger.
+type StructuredLogger interface {
+	Debug(msg string, keysAndValues ...interface{})
+	Info(msg string, keysAndValues ...interface{})
+	Warn(msg string, keysAndValues ...interface{})
+	Error(msg string, keysAndValues ...interface{})
+}
+
+// NoopStructuredLogger discards all events, it is used if neither PoolConfig.Logger
+// nor the Logger of the pool is set
+type NoopStructuredLogger struct{}
+
+func (l NoopStructuredLogger) Debug(msg string, keysAndValues ...interface{}) {}
+
+func (l NoopStructuredLogger) Info(msg string, keysAndValues ...interface{}) {}
+
+func (l NoopStructuredLogger) Warn(msg string, keysAndValues ...interface{}) {}
+
+func (l NoopStructuredLogger) Error(msg string, keysAndValues ...interface{}) {}
+
+// StdLogger writes the events with a logger of the standard log package as the message followed
+// by the key=value pairs, e.g.
+//
+//	conf.Logger = nebula_go.StdLogger{Logger: log.New(os.Stderr, "nebula ", log.LstdFlags)}
+//
+// nil Logger means the standard logger of the log package is used
+type StdLogger struct {
+	Logger *log.Logger
+	// Whether the debug events are written, they are discarded by default
+	EnableDebug bool
+}
+
+func (l StdLogger) Debug(msg string, keysAndValues ...interface{}) {
+	if l.EnableDebug {
+		l.output("[DEBUG]", msg, keysAndValues)
+	}
+}
+
+func (l StdLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.output("[INFO]", msg, keysAndValues)
+}
+
+func (l StdLogger) Warn(msg string, keysAndValues ...interface{}) {
+	l.output("[WARNING]", msg, keysAndValues)
+}
+
+func (l StdLogger) Error(msg string, keysAndValues ...interface{}) {
+	l.output("[ERROR]", msg, keysAndValues)
+}
+
+func (l StdLogger) output(level string, msg string, keysAndValues []interface{}) {
+	line := level + " " + formatKeyValues(msg, keysAndValues)
+	if l.Logger == nil {
+		log.Println(line)
+	} else {
+		l.Logger.Println(line)
+	}
+}
+
+// Logger receives the events of the connection pool and its sessions as formatted messages.
+// It is kept for compatibility, see StructuredLogger: the key-value pairs of the events are
+// appended to their messages and the debug events are discarded.
 type Logger interface {
 	Info(msg string)
 	Warn(msg string)
@@ -17,6 +86,7 @@ type Logger interface {
 	Fatal(msg string)
 }
 
+// DefaultLogger writes the events with the standard log package
 type DefaultLogger struct{}
 
 func (l DefaultLogger) Info(msg string) {
@@ -34,3 +104,68 @@ func (l DefaultLogger) Error(msg string) {
 func (l DefaultLogger) Fatal(msg string) {
 	log.Fatalf("[FATAL] %s\n", msg)
 }
+
+// NoopLogger discards all events
+type NoopLogger struct{}
+
+func (l NoopLogger) Info(msg string) {}
+
+func (l NoopLogger) Warn(msg string) {}
+
+func (l NoopLogger) Error(msg string) {}
+
+func (l NoopLogger) Fatal(msg string) {}
+
+// legacyLogger adapts a Logger to StructuredLogger
+type legacyLogger struct {
+	log Logger
+}
+
+func (l legacyLogger) Debug(msg string, keysAndValues ...interface{}) {}
+
+func (l legacyLogger) Info(msg string, keysAndValues ...interface{}) {
+	l.log.Info(formatKeyValues(msg, keysAndValues))
+}
+
+func (l legacyLogger) Warn(msg string, keysAndValues ...interface{}) {
+	l.log.Warn(formatKeyValues(msg, keysAndValues))
+}
+
+func (l legacyLogger) Error(msg string, keysAndValues ...interface{}) {
+	l.log.Error(formatKeyValues(msg, keysAndValues))
+}
+
+// Return the logger of the pool: the structured logger of the config,
+// or the given Logger adapted to it if it is not set
+func newPoolLogger(conf PoolConfig, log Logger) StructuredLogger {
+	switch {
+	case conf.Logger != nil:
+		return conf.Logger
+	case log != nil:
+		return legacyLogger{log}
+	default:
+		return NoopStructuredLogger{}
+	}
+}
+
+// Format the message followed by the key=value pairs, the values containing spaces,
+// quotes or equal signs are quoted. A missing value is formatted as MISSING.
+func formatKeyValues(msg string, keysAndValues []interface{}) string {
+	var b strings.Builder
+	b.WriteString(msg)
+	for i := 0; i < len(keysAndValues); i += 2 {
+		b.WriteByte(' ')
+		b.WriteString(fmt.Sprint(keysAndValues[i]))
+		b.WriteByte('=')
+		if i+1 == len(keysAndValues) {
+			b.WriteString("MISSING")
+			break
+		}
+		value := fmt.Sprint(keysAndValues[i+1])
+		if value == "" || strings.ContainsAny(value, " \t\n\"=") {
+			value = strconv.Quote(value)
+		}
+		b.WriteString(value)
+	}
+	return b.String()
+}
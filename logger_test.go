@@ -0,0 +1,56 @@
+/* Copyright (c) 2021 vesoft inc. All rights reserved.
+ *
+ * This source code is licensed under Apache 2.0 License,
+ * attached with Common Clause Condition 1.0, found in the LICENSES directory.
+ */
+
+package nebula_go
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// messageLogger records the messages of a Logger
+type messageLogger struct {
+	NoopLogger
+	infos []string
+}
+
+func (l *messageLogger) Info(msg string) {
+	l.infos = append(l.infos, msg)
+}
+
+func TestFormatKeyValues(t *testing.T) {
+	assert.Equal(t, "opened connection", formatKeyValues("opened connection", nil))
+	assert.Equal(t, `slow query host=127.0.0.1:3699 latency=200ms statement="YIELD \"a=b\"" error=EOF empty=""`,
+		formatKeyValues("slow query", []interface{}{"host", HostAddress{"127.0.0.1", 3699},
+			"latency", 200 * time.Millisecond, "statement", `YIELD "a=b"`, "error", fmt.Errorf("EOF"), "empty", ""}))
+	assert.Equal(t, "failed key=MISSING", formatKeyValues("failed", []interface{}{"key"}))
+}
+
+func TestPoolLogger(t *testing.T) {
+	legacy := &messageLogger{}
+	logger := newPoolLogger(GetDefaultConf(), legacy)
+	logger.Debug("opened connection", "host", HostAddress{"127.0.0.1", 3699})
+	logger.Info("reconnected", "session", int64(1))
+	assert.Equal(t, []string{"reconnected session=1"}, legacy.infos)
+
+	assert.Equal(t, NoopStructuredLogger{}, newPoolLogger(GetDefaultConf(), nil))
+	conf := GetDefaultConf()
+	conf.Logger = StdLogger{}
+	assert.Equal(t, StdLogger{}, newPoolLogger(conf, legacy))
+
+	var buf bytes.Buffer
+	std := StdLogger{Logger: log.New(&buf, "", 0)}
+	std.Debug("opened connection")
+	std.Warn("sign out failed", "session", int64(1), "error", fmt.Errorf("EOF"))
+	std.EnableDebug = true
+	std.Debug("opened connection")
+	assert.Equal(t, "[WARNING] sign out failed session=1 error=EOF\n[DEBUG] opened connection\n", buf.String())
+}
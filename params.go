@@ -0,0 +1,118 @@
+/* Copyright (c) 2021 vesoft inc. All rights reserved.
+ *
+ * This source code is licensed under Apache 2.0 License,
+ * attached with Common Clause Condition 1.0, found in the LICENSES directory.
+ */
+
+package nebula_go
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+
+	"github.com/vesoft-inc/nebula-go/v2/nebula"
+)
+
+// BuildParams converts the given Go values into nebula values.
+// Supported types are nil, bool, integers, floats, string, []byte, time.Time, *nebula.Value
+// and slices and maps with string keys of these types, which are converted into lists and maps.
+// A time.Time is converted into a datetime in UTC, its year must fit in an int16.
+// An error is returned if any of the values has an unsupported type.
+func BuildParams(m map[string]interface{}) (map[string]*nebula.Value, error) {
+	params := make(map[string]*nebula.Value, len(m))
+	for k, v := range m {
+		value, err := toNebulaValue(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build param %s, error: %s", k, err.Error())
+		}
+		params[k] = value
+	}
+	return params, nil
+}
+
+// Convert a Go value into a nebula value
+func toNebulaValue(v interface{}) (*nebula.Value, error) {
+	value := nebula.NewValue()
+	switch val := v.(type) {
+	case nil:
+		null := nebula.NullType___NULL__
+		value.NVal = &null
+	case *nebula.Value:
+		if val == nil {
+			return toNebulaValue(nil)
+		}
+		return val, nil
+	case bool:
+		value.BVal = &val
+	case []byte:
+		value.SVal = val
+	case string:
+		value.SVal = []byte(val)
+	case time.Time:
+		val = val.UTC()
+		if year := val.Year(); year < math.MinInt16 || year > math.MaxInt16 {
+			return nil, fmt.Errorf("year %d of time %s overflows int16", year, val)
+		}
+		value.DtVal = &nebula.DateTime{
+			Year:     int16(val.Year()),
+			Month:    int8(val.Month()),
+			Day:      int8(val.Day()),
+			Hour:     int8(val.Hour()),
+			Minute:   int8(val.Minute()),
+			Sec:      int8(val.Second()),
+			Microsec: int32(val.Nanosecond() / 1000),
+		}
+	default:
+		return reflectNebulaValue(reflect.ValueOf(v))
+	}
+	return value, nil
+}
+
+// Convert the numbers, slices and maps which are not matched by the type switch
+func reflectNebulaValue(rv reflect.Value) (*nebula.Value, error) {
+	value := nebula.NewValue()
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i := rv.Int()
+		value.IVal = &i
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u := rv.Uint()
+		if u > math.MaxInt64 {
+			return nil, fmt.Errorf("integer %d overflows int64", u)
+		}
+		i := int64(u)
+		value.IVal = &i
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		value.FVal = &f
+	case reflect.Slice, reflect.Array:
+		list := make([]*nebula.Value, 0, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			elem, err := toNebulaValue(rv.Index(i).Interface())
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, elem)
+		}
+		value.LVal = &nebula.NList{Values: list}
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("unsupported map key type %s", rv.Type().Key())
+		}
+		kvs := make(map[string]*nebula.Value, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			elem, err := toNebulaValue(iter.Value().Interface())
+			if err != nil {
+				return nil, err
+			}
+			kvs[iter.Key().String()] = elem
+		}
+		value.MVal = &nebula.NMap{Kvs: kvs}
+	default:
+		return nil, fmt.Errorf("unsupported type %T", rv.Interface())
+	}
+	return value, nil
+}
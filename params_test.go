@@ -0,0 +1,56 @@
+/* Copyright (c) 2021 vesoft inc. All rights reserved.
+ *
+ * This source code is licensed under Apache 2.0 License,
+ * attached with Common Clause Condition 1.0, found in the LICENSES directory.
+ */
+
+package nebula_go
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vesoft-inc/nebula-go/v2/nebula"
+)
+
+func TestBuildParams(t *testing.T) {
+	params, err := BuildParams(map[string]interface{}{
+		"null":  nil,
+		"bool":  true,
+		"int":   1,
+		"uint8": uint8(2),
+		"float": float32(1.5),
+		"str":   "abc",
+		"bytes": []byte("def"),
+		"time":  time.Date(2021, 3, 4, 5, 6, 7, 8000, time.FixedZone("UTC+8", 8*3600)),
+		"list":  []int{1, 2},
+		"map":   map[string]interface{}{"k": "v"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, nebula.NullType___NULL__, *params["null"].NVal)
+	assert.Equal(t, true, *params["bool"].BVal)
+	assert.Equal(t, int64(1), *params["int"].IVal)
+	assert.Equal(t, int64(2), *params["uint8"].IVal)
+	assert.Equal(t, 1.5, *params["float"].FVal)
+	assert.Equal(t, []byte("abc"), params["str"].SVal)
+	assert.Equal(t, []byte("def"), params["bytes"].SVal)
+	assert.Equal(t, nebula.DateTime{2021, 3, 3, 21, 6, 7, 8}, *params["time"].DtVal)
+	assert.Equal(t, 2, len(params["list"].LVal.Values))
+	assert.Equal(t, int64(2), *params["list"].LVal.Values[1].IVal)
+	assert.Equal(t, []byte("v"), params["map"].MVal.Kvs["k"].SVal)
+
+	_, err = BuildParams(map[string]interface{}{"struct": struct{}{}})
+	assert.NotNil(t, err)
+	_, err = BuildParams(map[string]interface{}{"map": map[int]int{1: 1}})
+	assert.NotNil(t, err)
+	_, err = BuildParams(map[string]interface{}{"uint64": uint64(1 << 63)})
+	assert.NotNil(t, err)
+	_, err = BuildParams(map[string]interface{}{"time": time.Date(40000, 1, 1, 0, 0, 0, 0, time.UTC)})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "year 40000 of time")
+	_, err = BuildParams(map[string]interface{}{"time": time.Date(-40000, 1, 1, 0, 0, 0, 0, time.UTC)})
+	assert.NotNil(t, err)
+}
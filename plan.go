@@ -0,0 +1,148 @@
+/* Copyright (c) 2021 vesoft inc. All rights reserved.
+ *
+ * This source code is licensed under Apache 2.0 License,
+ * attached with Common Clause Condition 1.0, found in the LICENSES directory.
+ */
+
+package nebula_go
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/vesoft-inc/nebula-go/v2/nebula/graph"
+)
+
+// PlanNode is a node of the execution plan tree built by BuildPlanTree.
+// The graph service does not estimate the rows or the cost of the nodes, the rows and the durations
+// of each execution of a node are only reported in Profiles when the statement is profiled.
+type PlanNode struct {
+	ID int64
+	// The operator, e.g. "Project" or "GetNeighbors"
+	Name        string
+	OutputVar   string
+	Description map[string]string
+	// The stats of each execution of the node, empty unless the statement is profiled
+	Profiles []*graph.ProfilingStats
+	// The nodes whose output is the input of this node
+	Dependencies []*PlanNode
+	// The last nodes of the branches of a Select or Loop node
+	Branches []*PlanNode
+	// Whether the node is the last node of the then branch of a Select node or the body of a Loop node,
+	// only meaningful for the nodes in Branches
+	IsDoBranch bool
+}
+
+// Children returns the dependencies followed by the branches of the node
+func (node *PlanNode) Children() []*PlanNode {
+	children := make([]*PlanNode, 0, len(node.Dependencies)+len(node.Branches))
+	children = append(children, node.Dependencies...)
+	return append(children, node.Branches...)
+}
+
+// Rows returns the total rows output by the executions of the node, 0 unless the statement is profiled
+func (node *PlanNode) Rows() int64 {
+	var rows int64
+	for _, profile := range node.Profiles {
+		rows += profile.GetRows()
+	}
+	return rows
+}
+
+// ExecDuration returns the total time spent executing the node, 0 unless the statement is profiled
+func (node *PlanNode) ExecDuration() time.Duration {
+	var us int64
+	for _, profile := range node.Profiles {
+		us += profile.GetExecDurationInUs()
+	}
+	return time.Duration(us) * time.Microsecond
+}
+
+// BuildPlanTree builds the tree of the plan description of an EXPLAIN or PROFILE statement
+// and returns its root, which is the node executed last.
+func BuildPlanTree(p *graph.PlanDescription) (*PlanNode, error) {
+	if p == nil || len(p.GetPlanNodeDescs()) == 0 {
+		return nil, fmt.Errorf("failed to build plan tree: the plan is empty")
+	}
+	nodes := make(map[int64]*PlanNode, len(p.GetPlanNodeDescs()))
+	for _, desc := range p.GetPlanNodeDescs() {
+		node := &PlanNode{
+			ID:          desc.GetId(),
+			Name:        string(desc.GetName()),
+			OutputVar:   string(desc.GetOutputVar()),
+			Description: make(map[string]string, len(desc.GetDescription())),
+			Profiles:    desc.GetProfiles(),
+		}
+		for _, pair := range desc.GetDescription() {
+			node.Description[string(pair.GetKey())] = string(pair.GetValue())
+		}
+		nodes[node.ID] = node
+	}
+	// The nodes which are neither a dependency nor a branch are roots
+	referenced := make(map[int64]bool, len(nodes))
+	for _, desc := range p.GetPlanNodeDescs() {
+		node := nodes[desc.GetId()]
+		for _, id := range desc.GetDependencies() {
+			dep, ok := nodes[id]
+			if !ok {
+				return nil, fmt.Errorf("failed to build plan tree: node %d depends on unknown node %d", node.ID, id)
+			}
+			node.Dependencies = append(node.Dependencies, dep)
+			referenced[id] = true
+		}
+		if desc.IsSetBranchInfo() {
+			info := desc.GetBranchInfo()
+			cond, ok := nodes[info.GetConditionNodeID()]
+			if !ok {
+				return nil, fmt.Errorf("failed to build plan tree: node %d is a branch of unknown node %d",
+					node.ID, info.GetConditionNodeID())
+			}
+			node.IsDoBranch = info.GetIsDoBranch()
+			cond.Branches = append(cond.Branches, node)
+			referenced[node.ID] = true
+		}
+	}
+	for _, desc := range p.GetPlanNodeDescs() {
+		if !referenced[desc.GetId()] {
+			return nodes[desc.GetId()], nil
+		}
+	}
+	return nil, fmt.Errorf("failed to build plan tree: every node is referenced by another node")
+}
+
+// Explain returns the execution plan of the statement without executing it.
+// An error is returned if the statement is rejected by the server, e.g. a statement which cannot
+// be explained such as an administrative statement, see BuildPlanTree to walk the plan.
+func (session *Session) Explain(stmt string) (*graph.PlanDescription, error) {
+	resSet, err := session.executePlan("EXPLAIN", stmt)
+	if err != nil {
+		return nil, err
+	}
+	return resSet.GetPlanDesc(), nil
+}
+
+// Profile executes the statement and returns its result with the execution plan holding the stats
+// of each node, see Explain.
+func (session *Session) Profile(stmt string) (*ResultSet, error) {
+	return session.executePlan("PROFILE", stmt)
+}
+
+// Execute the statement prefixed with EXPLAIN or PROFILE and check the plan is returned
+func (session *Session) executePlan(prefix, stmt string) (*ResultSet, error) {
+	trimmed := strings.TrimSpace(stmt)
+	if trimmed == "" {
+		return nil, fmt.Errorf("failed to %s: the statement is empty", strings.ToLower(prefix))
+	}
+	if first := strings.ToUpper(strings.Fields(trimmed)[0]); first == "EXPLAIN" || first == "PROFILE" {
+		return nil, fmt.Errorf("failed to %s: the statement is already explained or profiled", strings.ToLower(prefix))
+	}
+	resSet, err := session.ExecuteAndCheck(prefix + " " + trimmed)
+	if err != nil {
+		return nil, err
+	}
+	if !resSet.IsSetPlanDesc() {
+		return nil, fmt.Errorf("failed to %s: no plan is returned for the statement", strings.ToLower(prefix))
+	}
+	return resSet, nil
+}
@@ -0,0 +1,95 @@
+/* Copyright (c) 2021 vesoft inc. All rights reserved.
+ *
+ * This source code is licensed under Apache 2.0 License,
+ * attached with Common Clause Condition 1.0, found in the LICENSES directory.
+ */
+
+package nebula_go
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vesoft-inc/nebula-go/v2/fake"
+	"github.com/vesoft-inc/nebula-go/v2/nebula"
+	"github.com/vesoft-inc/nebula-go/v2/nebula/graph"
+)
+
+// Project_4 <- Select_3 <- Start_0, with the branches Project_1 and Project_2 of Select_3
+func getSelectPlan() *graph.PlanDescription {
+	return &graph.PlanDescription{
+		PlanNodeDescs: []*graph.PlanNodeDescription{
+			{Name: []byte("Project"), Id: 4, OutputVar: []byte("__Project_4"), Dependencies: []int64{3},
+				Description: []*graph.Pair{{Key: []byte("inputVar"), Value: []byte("__Select_3")}},
+				Profiles: []*graph.ProfilingStats{
+					{Rows: 2, ExecDurationInUs: 10},
+					{Rows: 3, ExecDurationInUs: 20},
+				}},
+			{Name: []byte("Select"), Id: 3, OutputVar: []byte("__Select_3"), Dependencies: []int64{0}},
+			{Name: []byte("Project"), Id: 2, OutputVar: []byte("__Project_2"),
+				BranchInfo: &graph.PlanNodeBranchInfo{IsDoBranch: false, ConditionNodeID: 3}},
+			{Name: []byte("Project"), Id: 1, OutputVar: []byte("__Project_1"),
+				BranchInfo: &graph.PlanNodeBranchInfo{IsDoBranch: true, ConditionNodeID: 3}},
+			{Name: []byte("Start"), Id: 0, OutputVar: []byte("__Start_0")},
+		},
+		NodeIndexMap: map[int64]int64{4: 0, 3: 1, 2: 2, 1: 3, 0: 4},
+		Format:       []byte("row"),
+	}
+}
+
+func TestBuildPlanTree(t *testing.T) {
+	root, err := BuildPlanTree(getSelectPlan())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, int64(4), root.ID)
+	assert.Equal(t, "Project", root.Name)
+	assert.Equal(t, "__Select_3", root.Description["inputVar"])
+	assert.Equal(t, int64(5), root.Rows())
+	assert.Equal(t, 30*time.Microsecond, root.ExecDuration())
+
+	sel := root.Dependencies[0]
+	assert.Equal(t, "Select", sel.Name)
+	assert.Equal(t, "Start", sel.Dependencies[0].Name)
+	assert.Len(t, sel.Branches, 2)
+	assert.Equal(t, int64(2), sel.Branches[0].ID)
+	assert.False(t, sel.Branches[0].IsDoBranch)
+	assert.True(t, sel.Branches[1].IsDoBranch)
+	assert.Len(t, sel.Children(), 3)
+	assert.Equal(t, int64(0), sel.Dependencies[0].Rows())
+
+	_, err = BuildPlanTree(nil)
+	assert.NotNil(t, err)
+	invalid := getSelectPlan()
+	invalid.PlanNodeDescs[1].Dependencies = []int64{5}
+	_, err = BuildPlanTree(invalid)
+	assert.NotNil(t, err)
+}
+
+func TestExplain(t *testing.T) {
+	service := fake.NewGraphService()
+	service.SetResponse("EXPLAIN GO FROM \"p1\" OVER follow", &graph.ExecutionResponse{
+		ErrorCode: nebula.ErrorCode_SUCCEEDED,
+		PlanDesc:  getSelectPlan(),
+	})
+	service.SetError("EXPLAIN SHOW HOSTS", nebula.ErrorCode_E_SYNTAX_ERROR, "syntax error near `SHOW'")
+	session, closeSession := newFakeSession(t, service)
+	defer closeSession()
+
+	plan, err := session.Explain("  GO FROM \"p1\" OVER follow ")
+	assert.Nil(t, err)
+	assert.Len(t, plan.GetPlanNodeDescs(), 5)
+
+	_, err = session.Explain("SHOW HOSTS")
+	assert.NotNil(t, err)
+	// The fake returns no plan for the statements without a canned response
+	_, err = session.Profile("YIELD 1")
+	assert.NotNil(t, err)
+	_, err = session.Profile("explain YIELD 1")
+	assert.NotNil(t, err)
+	_, err = session.Explain(" ")
+	assert.NotNil(t, err)
+	assert.Equal(t, []string{"EXPLAIN GO FROM \"p1\" OVER follow", "EXPLAIN SHOW HOSTS", "PROFILE YIELD 1"},
+		service.Executed())
+}
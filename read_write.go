@@ -0,0 +1,117 @@
+/* Copyright (c) 2021 vesoft inc. All rights reserved.
+ *
+ * This source code is licensed under Apache 2.0 License,
+ * attached with Common Clause Condition 1.0, found in the LICENSES directory.
+ */
+
+package nebula_go
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+)
+
+// ReadWriteConfig is the configuration of a ReadWritePool
+type ReadWriteConfig struct {
+	// The graph services dedicated to the reads
+	ReadHosts []HostAddress
+	// The graph services dedicated to the writes
+	WriteHosts []HostAddress
+	// The classifier of the read statements, the statements which are not classified as reads
+	// are sent to the write hosts
+	// nil value means IsReadOnlyStmt, which classifies the ambiguous statements as writes
+	IsRead func(stmt string) bool
+}
+
+// ReadWritePool holds a connection pool to the read hosts and one to the write hosts,
+// its sessions route each query to one of them according to the classifier of the config.
+type ReadWritePool struct {
+	read   *ConnectionPool
+	write  *ConnectionPool
+	isRead func(stmt string) bool
+}
+
+// NewReadWritePool creates the pools of the read and the write hosts with the pool config,
+// sslConfig is nil if the connections are not established with ssl
+func NewReadWritePool(conf ReadWriteConfig, poolConf PoolConfig, sslConfig *tls.Config,
+	log Logger) (*ReadWritePool, error) {
+	if len(conf.ReadHosts) == 0 || len(conf.WriteHosts) == 0 {
+		return nil, fmt.Errorf("failed to initialize read write pool: both the read and the write hosts are required")
+	}
+	write, err := NewSslConnectionPool(conf.WriteHosts, poolConf, sslConfig, log)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize the pool of the write hosts, %w", err)
+	}
+	read, err := NewSslConnectionPool(conf.ReadHosts, poolConf, sslConfig, log)
+	if err != nil {
+		write.Close()
+		return nil, fmt.Errorf("failed to initialize the pool of the read hosts, %w", err)
+	}
+	isRead := conf.IsRead
+	if isRead == nil {
+		isRead = IsReadOnlyStmt
+	}
+	return &ReadWritePool{read: read, write: write, isRead: isRead}, nil
+}
+
+// GetSession authenticates a session on a read host and a session on a write host
+func (pool *ReadWritePool) GetSession(username, password string) (*ReadWriteSession, error) {
+	write, err := pool.write.GetSession(username, password)
+	if err != nil {
+		return nil, err
+	}
+	read, err := pool.read.GetSession(username, password)
+	if err != nil {
+		write.Release()
+		return nil, err
+	}
+	return &ReadWriteSession{read: read, write: write, isRead: pool.isRead}, nil
+}
+
+// Close closes the pools of the read and the write hosts
+func (pool *ReadWritePool) Close() {
+	pool.read.Close()
+	pool.write.Close()
+}
+
+// ReadWriteSession executes the read queries in a session on a read host and the other queries
+// in a session on a write host. The space of the sessions is switched with Use, or set by the
+// SpaceName of the pool config: a USE statement passed to Execute only switches the write session.
+type ReadWriteSession struct {
+	read   *Session
+	write  *Session
+	isRead func(stmt string) bool
+}
+
+// Execute executes the query in the read or the write session, see Session.Execute
+func (session *ReadWriteSession) Execute(stmt string) (*ResultSet, error) {
+	return session.ExecuteWithContext(context.Background(), stmt)
+}
+
+// ExecuteWithContext executes the query in the read or the write session, see Session.ExecuteWithContext
+func (session *ReadWriteSession) ExecuteWithContext(ctx context.Context, stmt string) (*ResultSet, error) {
+	return session.route(stmt).ExecuteWithContext(ctx, stmt)
+}
+
+// Use switches both sessions to the space, see Session.Use
+func (session *ReadWriteSession) Use(space string) error {
+	if err := session.write.Use(space); err != nil {
+		return err
+	}
+	return session.read.Use(space)
+}
+
+// Release releases both sessions
+func (session *ReadWriteSession) Release() {
+	session.read.Release()
+	session.write.Release()
+}
+
+// Return the session the query is routed to
+func (session *ReadWriteSession) route(stmt string) *Session {
+	if session.isRead(stmt) {
+		return session.read
+	}
+	return session.write
+}
@@ -0,0 +1,82 @@
+/* Copyright (c) 2021 vesoft inc. All rights reserved.
+ *
+ * This source code is licensed under Apache 2.0 License,
+ * attached with Common Clause Condition 1.0, found in the LICENSES directory.
+ */
+
+package nebula_go
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vesoft-inc/nebula-go/v2/fake"
+)
+
+func TestReadWritePool(t *testing.T) {
+	readHost, writeHost := HostAddress{"127.0.0.1", 3699}, HostAddress{"127.0.0.2", 3699}
+	services := map[HostAddress]*fake.GraphService{
+		readHost:  fake.NewGraphService(),
+		writeHost: fake.NewGraphService(),
+	}
+	conf := GetDefaultConf()
+	conf.GraphServiceFactory = func(host HostAddress) (GraphService, error) {
+		return services[host], nil
+	}
+	rwConf := ReadWriteConfig{ReadHosts: []HostAddress{readHost}, WriteHosts: []HostAddress{writeHost}}
+	pool, err := NewReadWritePool(rwConf, conf, nil, NoopLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := pool.GetSession("root", "nebula")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Nil(t, session.Use("test"))
+	for _, stmt := range []string{
+		"MATCH (v) RETURN v LIMIT 1",
+		"INSERT VERTEX player(name) VALUES \"p1\":(\"Tim\")",
+		"GO FROM \"p1\" OVER follow | DELETE VERTEX $-.id",
+		"$var = GO FROM \"p1\" OVER follow",
+		"FETCH PROP ON player \"p1\"",
+	} {
+		_, err = session.Execute(stmt)
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, []string{"USE `test`", "MATCH (v) RETURN v LIMIT 1", "FETCH PROP ON player \"p1\""},
+		services[readHost].Executed())
+	assert.Equal(t, []string{
+		"USE `test`",
+		"INSERT VERTEX player(name) VALUES \"p1\":(\"Tim\")",
+		"GO FROM \"p1\" OVER follow | DELETE VERTEX $-.id",
+		"$var = GO FROM \"p1\" OVER follow",
+	}, services[writeHost].Executed())
+	assert.Equal(t, "test", session.read.CurrentSpace())
+
+	session.Release()
+	pool.Close()
+	assert.Equal(t, 0, services[readHost].Sessions())
+	assert.Equal(t, 0, services[writeHost].Sessions())
+
+	// Custom classifier
+	rwConf.IsRead = func(stmt string) bool {
+		return strings.HasPrefix(stmt, "CALL")
+	}
+	pool, err = NewReadWritePool(rwConf, conf, nil, NoopLogger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	session, err = pool.GetSession("root", "nebula")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer session.Release()
+	assert.Equal(t, session.read, session.route("CALL db.labels()"))
+	assert.Equal(t, session.write, session.route("MATCH (v) RETURN v"))
+
+	_, err = NewReadWritePool(ReadWriteConfig{WriteHosts: []HostAddress{writeHost}}, conf, nil, NoopLogger{})
+	assert.NotNil(t, err)
+}
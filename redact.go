@@ -0,0 +1,74 @@
+/* Copyright (c) 2021 vesoft inc. All rights reserved.
+ *
+ * This source code is licensed under Apache 2.0 License,
+ * attached with Common Clause Condition 1.0, found in the LICENSES directory.
+ */
+
+package nebula_go
+
+import (
+	"strings"
+	"unicode"
+)
+
+// RedactLiterals replaces the string and number literals of the statement with ?, e.g.
+//
+//	INSERT VERTEX player(name, age) VALUES "p1":("Tim", 42)
+//
+// becomes
+//
+//	INSERT VERTEX player(name, age) VALUES ?:(?, ?)
+//
+// The identifiers, including those quoted by backticks, are kept.
+func RedactLiterals(stmt string) string {
+	var b strings.Builder
+	runes := []rune(stmt)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '"' || r == '\'':
+			// Skip to the closing quote, an unterminated string is masked to the end
+			for i++; i < len(runes) && runes[i] != r; i++ {
+				if runes[i] == '\\' {
+					i++
+				}
+			}
+			b.WriteRune('?')
+		case r == '`':
+			end := i + 1
+			for end < len(runes) && runes[end] != '`' {
+				end++
+			}
+			if end == len(runes) {
+				end--
+			}
+			b.WriteString(string(runes[i : end+1]))
+			i = end
+		case unicode.IsDigit(r) && (i == 0 || !isIdentRune(runes[i-1])):
+			// A number such as 42, 1.5e3 or 0x1F
+			for i+1 < len(runes) && (isIdentRune(runes[i+1]) || runes[i+1] == '.') {
+				i++
+			}
+			b.WriteRune('?')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Check if the rune can be part of an identifier or a number
+func isIdentRune(r rune) bool {
+	return r == '_' || r == '$' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// Redact the statement for the logs and the traces as set by the pool config
+func (conf *PoolConfig) redactStmt(stmt string) string {
+	if conf.RedactStmt != nil {
+		return conf.RedactStmt(stmt)
+	}
+	if conf.RedactLiterals {
+		return RedactLiterals(stmt)
+	}
+	return stmt
+}
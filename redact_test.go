@@ -0,0 +1,37 @@
+/* Copyright (c) 2021 vesoft inc. All rights reserved.
+ *
+ * This source code is licensed under Apache 2.0 License,
+ * attached with Common Clause Condition 1.0, found in the LICENSES directory.
+ */
+
+package nebula_go
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactLiterals(t *testing.T) {
+	cases := map[string]string{
+		`INSERT VERTEX player(name, age) VALUES "p1":("Tim", 42)`:     `INSERT VERTEX player(name, age) VALUES ?:(?, ?)`,
+		`INSERT EDGE follow(degree) VALUES 1->2@0:(95.5)`:             `INSERT EDGE follow(degree) VALUES ?->?@?:(?)`,
+		`MATCH (v:player) WHERE v.name == 'O\'Neal' RETURN v.age2`:    `MATCH (v:player) WHERE v.name == ? RETURN v.age2`,
+		"FETCH PROP ON `tag 1` \"a\\\"b\" YIELD `tag 1`.p2, $-.id":    "FETCH PROP ON `tag 1` ? YIELD `tag 1`.p2, $-.id",
+		`YIELD 1e3 + 0x1F, -7, "unterminated`:                         `YIELD ? + ?, -?, ?`,
+		"USE `unterminated":                                           "USE `unterminated",
+		`GO FROM "p1" OVER follow YIELD follow._dst AS id | LIMIT 10`: `GO FROM ? OVER follow YIELD follow._dst AS id | LIMIT ?`,
+	}
+	for stmt, expected := range cases {
+		assert.Equal(t, expected, RedactLiterals(stmt), stmt)
+	}
+
+	conf := PoolConfig{}
+	assert.Equal(t, `YIELD "x"`, conf.redactStmt(`YIELD "x"`))
+	conf.RedactLiterals = true
+	assert.Equal(t, `YIELD ?`, conf.redactStmt(`YIELD "x"`))
+	conf.RedactStmt = func(stmt string) string {
+		return "redacted"
+	}
+	assert.Equal(t, "redacted", conf.redactStmt(`YIELD "x"`))
+}
@@ -10,6 +10,7 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"sort"
 	"strings"
 	"time"
@@ -23,6 +24,8 @@ type ResultSet struct {
 	columnNames     []string
 	colNameIndexMap map[string]int
 	timezoneInfo    timezoneInfo
+	retryCount      int
+	hostAddress     HostAddress
 }
 
 type Record struct {
@@ -44,7 +47,8 @@ type Relationship struct {
 	timezoneInfo timezoneInfo
 }
 
-type segment struct {
+// Segment is a step of a path, as the relationship with the nodes it starts from and points to
+type Segment struct {
 	startNode    *Node
 	relationship *Relationship
 	endNode      *Node
@@ -54,7 +58,7 @@ type PathWrapper struct {
 	path             *nebula.Path
 	nodeList         []*Node
 	relationshipList []*Relationship
-	segments         []segment
+	segments         []Segment
 	timezoneInfo     timezoneInfo
 }
 
@@ -89,6 +93,7 @@ const (
 	ErrorCode_E_BAD_PERMISSION        ErrorCode = ErrorCode(nebula.ErrorCode_E_BAD_PERMISSION)
 	ErrorCode_E_SEMANTIC_ERROR        ErrorCode = ErrorCode(nebula.ErrorCode_E_SEMANTIC_ERROR)
 	ErrorCode_E_PARTIAL_SUCCEEDED     ErrorCode = ErrorCode(nebula.ErrorCode_E_PARTIAL_SUCCEEDED)
+	ErrorCode_E_SPACE_NOT_FOUND       ErrorCode = ErrorCode(nebula.ErrorCode_E_SPACE_NOT_FOUND)
 )
 
 func genResultSet(resp *graph.ExecutionResponse, timezoneInfo timezoneInfo) (*ResultSet, error) {
@@ -169,7 +174,7 @@ func genPathWrapper(path *nebula.Path, timezoneInfo timezoneInfo) (*PathWrapper,
 	var (
 		nodeList         []*Node
 		relationshipList []*Relationship
-		segList          []segment
+		segList          []Segment
 		edge             *nebula.Edge
 		segStartNode     *Node
 		segEndNode       *Node
@@ -223,7 +228,7 @@ func genPathWrapper(path *nebula.Path, timezoneInfo timezoneInfo) (*PathWrapper,
 				return nil, fmt.Errorf("failed to generate PathWrapper, Path received is invalid")
 			}
 		}
-		segList = append(segList, segment{
+		segList = append(segList, Segment{
 			startNode:    segStartNode,
 			relationship: relationship,
 			endNode:      segEndNode,
@@ -269,6 +274,51 @@ func (res ResultSet) GetValuesByColName(colName string) ([]*ValueWrapper, error)
 	return valList, nil
 }
 
+// ColumnIndex returns the index of the given column, ok is false if the column does not exist.
+// The index is looked up in a map built once with the result set, so it can be used to access
+// the values of many records by index.
+func (res ResultSet) ColumnIndex(colName string) (index int, ok bool) {
+	index, ok = res.colNameIndexMap[colName]
+	return index, ok
+}
+
+// ColumnInfo is the metadata of a column of a result set
+type ColumnInfo struct {
+	Name string `json:"name"`
+	// The value type as returned by ValueWrapper.GetType, e.g. "int" or "datetime",
+	// empty if it cannot be inferred
+	Type string `json:"type"`
+}
+
+// ColumnMeta returns the name and the value type of each column.
+// The graph service does not send the declared types of the columns, so the type of a column
+// is inferred from its value in the first row where it is neither null nor empty, which is
+// computed on each call. The type is empty if there is no such row, e.g. if the result is empty.
+// Since only one value is inspected, a column whose values have different types, e.g. an
+// expression or a property of different tags, is reported with the type of that value only.
+func (res ResultSet) ColumnMeta() []ColumnInfo {
+	columns := make([]ColumnInfo, len(res.columnNames))
+	unknown := len(columns)
+	for i, name := range res.columnNames {
+		columns[i].Name = name
+	}
+	for _, row := range res.GetRows() {
+		if unknown == 0 {
+			break
+		}
+		for i, val := range row.Values {
+			if i >= len(columns) || columns[i].Type != "" {
+				continue
+			}
+			if typ := (ValueWrapper{val, res.timezoneInfo}).GetType(); typ != "null" && typ != "empty" {
+				columns[i].Type = typ
+				unknown--
+			}
+		}
+	}
+	return columns
+}
+
 // Returns all values in the row at given index
 func (res ResultSet) GetRowValuesByIndex(index int) (*Record, error) {
 	if err := checkIndex(index, res.resp.Data.Rows); err != nil {
@@ -286,6 +336,42 @@ func (res ResultSet) GetRowValuesByIndex(index int) (*Record, error) {
 	}, nil
 }
 
+// ResultStream yields the records of a ResultSet one at a time.
+//
+// Nebula Graph does not page results on the server side, the whole response is received
+// before the first record is returned. Each row is released from the response once its record
+// is returned, so the records can be processed and discarded incrementally instead of being
+// kept in memory until the whole result is processed.
+type ResultStream struct {
+	resSet *ResultSet
+	index  int
+}
+
+func newResultStream(resSet *ResultSet) *ResultStream {
+	return &ResultStream{resSet: resSet}
+}
+
+// Next returns the next record, io.EOF is returned when all records have been returned
+func (stream *ResultStream) Next() (*Record, error) {
+	rows := stream.resSet.GetRows()
+	if stream.index >= len(rows) {
+		return nil, io.EOF
+	}
+	record, err := stream.resSet.GetRowValuesByIndex(stream.index)
+	if err != nil {
+		return nil, err
+	}
+	// Release the row from the response
+	rows[stream.index] = nil
+	stream.index++
+	return record, nil
+}
+
+// Returns the column names of the records
+func (stream *ResultStream) GetColNames() []string {
+	return stream.resSet.GetColNames()
+}
+
 // Returns the number of total rows
 func (res ResultSet) GetRowSize() int {
 	if res.resp.Data == nil {
@@ -337,6 +423,22 @@ func (res ResultSet) GetLatency() int32 {
 	return res.resp.LatencyInUs
 }
 
+// GetRetryCount returns the number of times the query was executed again
+// after a leader change or a transient storage error
+func (res ResultSet) GetRetryCount() int {
+	return res.retryCount
+}
+
+// GetHostAddress returns the address of the host which served the query
+func (res ResultSet) GetHostAddress() HostAddress {
+	return res.hostAddress
+}
+
+// Latency returns the execution latency reported by the server
+func (res ResultSet) Latency() time.Duration {
+	return time.Duration(res.resp.LatencyInUs) * time.Microsecond
+}
+
 func (res ResultSet) GetSpaceName() string {
 	if res.resp.SpaceName == nil {
 		return ""
@@ -406,11 +508,10 @@ func (record Record) GetValueByIndex(index int) (*ValueWrapper, error) {
 
 // Returns value in the record at given column name
 func (record Record) GetValueByColName(colName string) (*ValueWrapper, error) {
-	if !record.hasColName(colName) {
+	index, ok := (*record.colNameIndexMap)[colName]
+	if !ok {
 		return nil, fmt.Errorf("failed to get values, given column name '%s' does not exist", colName)
 	}
-	// Get index
-	index := (*record.colNameIndexMap)[colName]
 	return record._record[index], nil
 }
 
@@ -422,13 +523,6 @@ func (record Record) String() string {
 	return strings.Join(strList, ", ")
 }
 
-func (record Record) hasColName(colName string) bool {
-	if _, ok := (*record.colNameIndexMap)[colName]; ok {
-		return true
-	}
-	return false
-}
-
 // getRawID returns a list of row vid
 func (node Node) getRawID() *nebula.Value {
 	return node.vertex.GetVid()
@@ -647,7 +741,10 @@ func (path *PathWrapper) GetRelationships() []*Relationship {
 	return path.relationshipList
 }
 
-func (path *PathWrapper) GetSegments() []segment {
+// GetSegments returns the steps of the path in order. The start node of a segment is the
+// node its relationship starts from, which is the later node of the path if the step is
+// traversed against the direction of the relationship.
+func (path *PathWrapper) GetSegments() []Segment {
 	return path.segments
 }
 
@@ -669,18 +766,35 @@ func (path *PathWrapper) ContainsRelationship(relationship *Relationship) bool {
 	return false
 }
 
+// GetStartNode returns the node the path starts from, it is also the end node of a path without steps
 func (path *PathWrapper) GetStartNode() (*Node, error) {
-	if len(path.segments) == 0 {
+	if len(path.nodeList) == 0 {
 		return nil, fmt.Errorf("failed to get start node, no node in the path")
 	}
-	return path.segments[0].startNode, nil
+	return path.nodeList[0], nil
 }
 
+// GetEndNode returns the node the path ends at, it is also the start node of a path without steps
 func (path *PathWrapper) GetEndNode() (*Node, error) {
-	if len(path.segments) == 0 {
-		return nil, fmt.Errorf("failed to get start node, no node in the path")
+	if len(path.nodeList) == 0 {
+		return nil, fmt.Errorf("failed to get end node, no node in the path")
 	}
-	return path.segments[len(path.segments)-1].endNode, nil
+	return path.nodeList[len(path.nodeList)-1], nil
+}
+
+// GetStartNode returns the node the relationship of the segment starts from
+func (seg Segment) GetStartNode() *Node {
+	return seg.startNode
+}
+
+// GetRelationship returns the relationship of the segment
+func (seg Segment) GetRelationship() *Relationship {
+	return seg.relationship
+}
+
+// GetEndNode returns the node the relationship of the segment points to
+func (seg Segment) GetEndNode() *Node {
+	return seg.endNode
 }
 
 // Path format: <("VertexID" :tag1{k0: v0,k1: v1})
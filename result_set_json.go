@@ -0,0 +1,225 @@
+/* Copyright (c) 2021 vesoft inc. All rights reserved.
+ *
+ * This source code is licensed under Apache 2.0 License,
+ * attached with Common Clause Condition 1.0, found in the LICENSES directory.
+ */
+
+package nebula_go
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/vesoft-inc/nebula-go/v2/nebula"
+)
+
+// MarshalJSON encodes the result set as
+//
+//	{"error_code": 0, "error_msg": "", "space_name": "", "latency_in_us": 100,
+//	 "columns": ["col1", "col2"], "rows": [[value, value], ...]}
+//
+// where each value is encoded as described in ValueWrapper.MarshalJSON.
+func (res ResultSet) MarshalJSON() ([]byte, error) {
+	rows := make([]interface{}, 0, res.GetRowSize())
+	for _, row := range res.GetRows() {
+		rows = append(rows, rowToJSON(row, res.timezoneInfo))
+	}
+	columns := res.GetColNames()
+	if columns == nil {
+		columns = []string{}
+	}
+	return json.Marshal(map[string]interface{}{
+		"error_code":    res.GetErrorCode(),
+		"error_msg":     res.GetErrorMsg(),
+		"space_name":    res.GetSpaceName(),
+		"latency_in_us": res.GetLatency(),
+		"columns":       columns,
+		"rows":          rows,
+	})
+}
+
+// MarshalJSON encodes the value keeping its nebula type.
+// Null, bool, int, float, string and list are encoded as the JSON value of the same type,
+// the other types are encoded as an object whose "type" is the name returned by GetType:
+//
+//	date, time, datetime: {"type": "date", "value": "2021-01-02"}, the value is formatted as by String
+//	float which is NaN or infinite: {"type": "float", "value": "NaN"}
+//	set: {"type": "set", "values": [value, ...]}
+//	map: {"type": "map", "kvs": {"key": value, ...}}
+//	vertex: {"type": "vertex", "vid": value, "tags": [{"name": "tag", "props": {"prop": value, ...}}, ...]}
+//	edge: {"type": "edge", "src": value, "dst": value, "name": "edge", "ranking": 0, "props": {"prop": value, ...}}
+//	path: {"type": "path", "nodes": [vertex, ...], "relationships": [edge, ...]}
+//	dataset: {"type": "dataset", "columns": ["col", ...], "rows": [[value, ...], ...]}
+//
+// The src and dst of an edge are the vertices the edge starts from and points to.
+func (valWrap ValueWrapper) MarshalJSON() ([]byte, error) {
+	return json.Marshal(valueToJSON(valWrap.value, valWrap.timezoneInfo))
+}
+
+// Convert the value into its JSON representation
+func valueToJSON(value *nebula.Value, timezoneInfo timezoneInfo) interface{} {
+	if value == nil {
+		return nil
+	}
+	valWrap := ValueWrapper{value, timezoneInfo}
+	switch {
+	case value.IsSetNVal():
+		return nil
+	case value.IsSetBVal():
+		return value.GetBVal()
+	case value.IsSetIVal():
+		return value.GetIVal()
+	case value.IsSetFVal():
+		f := value.GetFVal()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return map[string]interface{}{"type": "float", "value": strconv.FormatFloat(f, 'f', -1, 64)}
+		}
+		return f
+	case value.IsSetSVal():
+		return string(value.GetSVal())
+	case value.IsSetDVal(), value.IsSetTVal(), value.IsSetDtVal():
+		return map[string]interface{}{"type": valWrap.GetType(), "value": valWrap.String()}
+	case value.IsSetLVal():
+		return listToJSON(value.GetLVal().Values, timezoneInfo)
+	case value.IsSetUVal():
+		return map[string]interface{}{"type": "set", "values": listToJSON(value.GetUVal().Values, timezoneInfo)}
+	case value.IsSetMVal():
+		return map[string]interface{}{"type": "map", "kvs": propsToJSON(value.GetMVal().Kvs, timezoneInfo)}
+	case value.IsSetVVal():
+		return vertexToJSON(value.GetVVal(), timezoneInfo)
+	case value.IsSetEVal():
+		relationship, _ := genRelationship(value.GetEVal(), timezoneInfo)
+		return relationshipToJSON(relationship)
+	case value.IsSetPVal():
+		pathWrap, err := genPathWrapper(value.GetPVal(), timezoneInfo)
+		if err != nil {
+			return map[string]interface{}{"type": "path", "nodes": []interface{}{}, "relationships": []interface{}{}}
+		}
+		nodes := make([]interface{}, 0, len(pathWrap.GetNodes()))
+		for _, node := range pathWrap.GetNodes() {
+			nodes = append(nodes, vertexToJSON(node.vertex, timezoneInfo))
+		}
+		relationships := make([]interface{}, 0, len(pathWrap.GetRelationships()))
+		for _, relationship := range pathWrap.GetRelationships() {
+			relationships = append(relationships, relationshipToJSON(relationship))
+		}
+		return map[string]interface{}{"type": "path", "nodes": nodes, "relationships": relationships}
+	case value.IsSetGVal():
+		dataset := value.GetGVal()
+		columns := make([]string, 0, len(dataset.ColumnNames))
+		for _, name := range dataset.ColumnNames {
+			columns = append(columns, string(name))
+		}
+		rows := make([]interface{}, 0, len(dataset.Rows))
+		for _, row := range dataset.Rows {
+			rows = append(rows, rowToJSON(row, timezoneInfo))
+		}
+		return map[string]interface{}{"type": "dataset", "columns": columns, "rows": rows}
+	}
+	return nil
+}
+
+func rowToJSON(row *nebula.Row, timezoneInfo timezoneInfo) []interface{} {
+	return listToJSON(row.GetValues(), timezoneInfo)
+}
+
+func listToJSON(values []*nebula.Value, timezoneInfo timezoneInfo) []interface{} {
+	list := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		list = append(list, valueToJSON(value, timezoneInfo))
+	}
+	return list
+}
+
+func propsToJSON(props map[string]*nebula.Value, timezoneInfo timezoneInfo) map[string]interface{} {
+	kvs := make(map[string]interface{}, len(props))
+	for k, v := range props {
+		kvs[k] = valueToJSON(v, timezoneInfo)
+	}
+	return kvs
+}
+
+func vertexToJSON(vertex *nebula.Vertex, timezoneInfo timezoneInfo) map[string]interface{} {
+	tags := make([]interface{}, 0, len(vertex.GetTags()))
+	for _, tag := range vertex.GetTags() {
+		tags = append(tags, map[string]interface{}{
+			"name":  string(tag.GetName()),
+			"props": propsToJSON(tag.GetProps(), timezoneInfo),
+		})
+	}
+	return map[string]interface{}{
+		"type": "vertex",
+		"vid":  valueToJSON(vertex.GetVid(), timezoneInfo),
+		"tags": tags,
+	}
+}
+
+func relationshipToJSON(relationship *Relationship) map[string]interface{} {
+	src := relationship.GetSrcVertexID()
+	dst := relationship.GetDstVertexID()
+	return map[string]interface{}{
+		"type":    "edge",
+		"src":     valueToJSON(src.value, relationship.timezoneInfo),
+		"dst":     valueToJSON(dst.value, relationship.timezoneInfo),
+		"name":    relationship.GetEdgeName(),
+		"ranking": relationship.GetRanking(),
+		"props":   propsToJSON(relationship.edge.GetProps(), relationship.timezoneInfo),
+	}
+}
+
+// The fields of the response of ExecuteJson used by the session, e.g.
+//
+//	{"errors": [{"code": 0}], "results": [{"spaceName": "test", "latencyInUs": 100, "columns": [...], "data": [...]}]}
+//
+// The fields are zero if the response cannot be decoded.
+type jsonResponse struct {
+	Errors []struct {
+		Code    ErrorCode `json:"code"`
+		Message string    `json:"message"`
+	} `json:"errors"`
+	Results []struct {
+		SpaceName   string `json:"spaceName"`
+		LatencyInUs int64  `json:"latencyInUs"`
+	} `json:"results"`
+}
+
+func parseJsonResponse(resp []byte) jsonResponse {
+	var parsed jsonResponse
+	if err := json.Unmarshal(resp, &parsed); err != nil {
+		return jsonResponse{}
+	}
+	return parsed
+}
+
+// Return the error reported by the server, nil if the query succeeded
+func (resp jsonResponse) err() error {
+	if len(resp.Errors) == 0 || resp.Errors[0].Code == ErrorCode_SUCCEEDED {
+		return nil
+	}
+	code, msg := resp.Errors[0].Code, resp.Errors[0].Message
+	return &ExecutionError{
+		Msg:       fmt.Sprintf("ErrorCode: %v, ErrorMsg: %s", code, msg),
+		Code:      code,
+		ServerMsg: msg,
+	}
+}
+
+// Return the latency reported by the server
+func (resp jsonResponse) latency() time.Duration {
+	if len(resp.Results) == 0 {
+		return 0
+	}
+	return time.Duration(resp.Results[0].LatencyInUs) * time.Microsecond
+}
+
+// Return the space of the session after the query, false if the query failed or the response
+// cannot be decoded
+func (resp jsonResponse) space() (string, bool) {
+	if len(resp.Results) == 0 || resp.err() != nil {
+		return "", false
+	}
+	return resp.Results[0].SpaceName, true
+}
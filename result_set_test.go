@@ -7,9 +7,14 @@
 package nebula_go
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math"
 	"sort"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/vesoft-inc/nebula-go/v2/nebula"
@@ -82,6 +87,57 @@ func TestAsString(t *testing.T) {
 	assert.Equal(t, string(value.GetSVal()), res)
 }
 
+func TestToIntToFloat(t *testing.T) {
+	wrap := func(value nebula.Value) ValueWrapper {
+		return ValueWrapper{&value, testTimezone}
+	}
+	floatVal := func(f float64) ValueWrapper {
+		return wrap(nebula.Value{FVal: &f})
+	}
+	intVal := func(i int64) ValueWrapper {
+		return wrap(nebula.Value{IVal: &i})
+	}
+
+	res, err := intVal(-7).ToInt(false)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(-7), res)
+	res, err = floatVal(42.0).ToInt(false)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(42), res)
+	_, err = floatVal(-2.5).ToInt(false)
+	assert.EqualError(t, err, "failed to convert value float -2.5 to int, value has a fractional part")
+	res, err = floatVal(-2.5).ToInt(true)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(-2), res)
+	for _, f := range []float64{math.NaN(), math.Inf(1), math.Inf(-1), 1e19, -1e19} {
+		_, err = floatVal(f).ToInt(true)
+		assert.NotNil(t, err, f)
+	}
+
+	resFloat, err := intVal(1 << 53).ToFloat()
+	assert.Nil(t, err)
+	assert.Equal(t, float64(1<<53), resFloat)
+	resFloat, err = floatVal(0.5).ToFloat()
+	assert.Nil(t, err)
+	assert.Equal(t, 0.5, resFloat)
+	_, err = intVal(1<<53 + 1).ToFloat()
+	assert.NotNil(t, err)
+
+	// Strings are never coerced
+	str := wrap(nebula.Value{SVal: []byte("1")})
+	_, err = str.ToInt(true)
+	var convErr *ConversionError
+	assert.True(t, errors.As(err, &convErr))
+	assert.Equal(t, "string", convErr.Type)
+	assert.Equal(t, "int", convErr.Target)
+	assert.EqualError(t, err, "failed to convert value string to int")
+	_, err = str.ToFloat()
+	assert.NotNil(t, err)
+	_, err = str.AsBool()
+	assert.True(t, errors.As(err, &convErr))
+	assert.Equal(t, "bool", convErr.Target)
+}
+
 func TestAsList(t *testing.T) {
 	var valList = []*nebula.Value{
 		{SVal: []byte("elem1")},
@@ -152,6 +208,48 @@ func TestAsMap(t *testing.T) {
 	}
 }
 
+func TestAsNativeGo(t *testing.T) {
+	null := nebula.NullType___NULL__
+	value := nebula.Value{LVal: &nebula.NList{Values: []*nebula.Value{
+		setIVal(1),
+		{NVal: &null},
+		{MVal: &nebula.NMap{Kvs: map[string]*nebula.Value{
+			"set":    {UVal: &nebula.NSet{Values: []*nebula.Value{{SVal: []byte("a")}}}},
+			"vertex": {VVal: getVertex("Tom", 1, 1)},
+		}}},
+		{DVal: &nebula.Date{Year: 2021, Month: 1, Day: 2}},
+	}}}
+	native, err := ValueWrapper{&value, testTimezone}.AsNativeGo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	list := native.([]interface{})
+	assert.Equal(t, 4, len(list))
+	assert.Equal(t, int64(1), list[0])
+	assert.Nil(t, list[1])
+	kvs := list[2].(map[string]interface{})
+	assert.Equal(t, []interface{}{"a"}, kvs["set"])
+	node := kvs["vertex"].(*Node)
+	assert.Equal(t, []string{"tag0"}, node.GetTags())
+	date := list[3].(time.Time)
+	assert.Equal(t, 2021, date.Year())
+
+	native, err = ValueWrapper{&nebula.Value{}, testTimezone}.AsNativeGo()
+	assert.Nil(t, err)
+	assert.Nil(t, native)
+
+	dataset := nebula.Value{GVal: &nebula.DataSet{
+		ColumnNames: [][]byte{[]byte("name"), []byte("age")},
+		Rows: []*nebula.Row{
+			{Values: []*nebula.Value{{SVal: []byte("Tim")}, setIVal(42)}},
+			{Values: []*nebula.Value{{SVal: []byte("Tony")}, {NVal: &null}}},
+		},
+	}}
+	native, err = ValueWrapper{&dataset, testTimezone}.AsNativeGo()
+	assert.Nil(t, err)
+	assert.Equal(t, [][]interface{}{{"Tim", int64(42)}, {"Tony", nil}}, native)
+}
+
 func TestAsDate(t *testing.T) {
 	value := nebula.Value{DVal: &nebula.Date{2020, 12, 25}}
 	valWrap := ValueWrapper{&value, testTimezone}
@@ -271,6 +369,35 @@ func TestAsDateTime(t *testing.T) {
 	assert.Equal(t, expected, *localTime)
 }
 
+func TestAsGoTime(t *testing.T) {
+	timezoneInfo := timezoneInfo{8 * 3600, []byte("+08:00")}
+
+	value := nebula.Value{DVal: &nebula.Date{2020, 12, 25}}
+	goTime, err := ValueWrapper{&value, timezoneInfo}.AsGoTime()
+	if err != nil {
+		t.Error(err.Error())
+	}
+	assert.Equal(t, "2020-12-25T00:00:00+08:00", goTime.Format(time.RFC3339Nano))
+
+	value = nebula.Value{TVal: &nebula.Time{13, 12, 25, 29}}
+	goTime, err = ValueWrapper{&value, timezoneInfo}.AsGoTime()
+	if err != nil {
+		t.Error(err.Error())
+	}
+	assert.Equal(t, "0001-01-01T21:12:25.000029+08:00", goTime.Format(time.RFC3339Nano))
+
+	value = nebula.Value{DtVal: &nebula.DateTime{2020, 12, 25, 22, 12, 25, 29}}
+	goTime, err = ValueWrapper{&value, timezoneInfo}.AsGoTime()
+	if err != nil {
+		t.Error(err.Error())
+	}
+	assert.Equal(t, "2020-12-26T06:12:25.000029+08:00", goTime.Format(time.RFC3339Nano))
+
+	value = nebula.Value{IVal: new(int64)}
+	_, err = ValueWrapper{&value, timezoneInfo}.AsGoTime()
+	assert.EqualError(t, err, "failed to convert value int to time.Time")
+}
+
 func TestAsNode(t *testing.T) {
 	value := nebula.Value{VVal: getVertex("Adam", 3, 5)}
 	valWrap := ValueWrapper{&value, testTimezone}
@@ -510,6 +637,23 @@ func TestPathWrapper(t *testing.T) {
 	endNode, _ := pathWrapper.GetEndNode()
 	assert.Equal(t, "\"Tom\"", startNode.GetID().String())
 	assert.Equal(t, "\"vertex4\"", endNode.GetID().String())
+	assert.True(t, segList[1].GetRelationship().IsEqualTo(relationshipList[1]))
+	assert.Equal(t, "\"vertex1\"", segList[1].GetStartNode().GetID().String())
+	assert.Equal(t, "\"vertex0\"", segList[1].GetEndNode().GetID().String())
+
+	// A path without steps starts and ends at its only node
+	pathWrapper, err = genPathWrapper(getPath("Tom", 0), testTimezone)
+	if err != nil {
+		t.Errorf(err.Error())
+	}
+	assert.Equal(t, 0, pathWrapper.GetPathLength())
+	assert.Empty(t, pathWrapper.GetSegments())
+	startNode, err = pathWrapper.GetStartNode()
+	assert.Nil(t, err)
+	endNode, err = pathWrapper.GetEndNode()
+	assert.Nil(t, err)
+	assert.Equal(t, "\"Tom\"", startNode.GetID().String())
+	assert.Equal(t, "\"Tom\"", endNode.GetID().String())
 }
 
 func TestResultSet(t *testing.T) {
@@ -532,6 +676,13 @@ func TestResultSet(t *testing.T) {
 	assert.Equal(t, "", resultSetWithNil.GetSpaceName())
 	assert.Equal(t, "", resultSetWithNil.GetComment())
 	assert.Equal(t, false, resultSetWithNil.IsSucceed())
+	assert.Nil(t, resultSetWithNil.GetPlanDesc())
+	err = checkResultSet(resultSetWithNil)
+	assert.EqualError(t, err, "failed to execute, ErrorCode: -1006, ErrorMsg: ")
+	var execErr *ExecutionError
+	assert.True(t, errors.As(err, &execErr))
+	assert.Equal(t, ErrorCode_E_STATEMENT_EMPTY, execErr.Code)
+	assert.Equal(t, "", execErr.ServerMsg)
 
 	planDesc := graph.PlanDescription{
 		[]*graph.PlanNodeDescription{
@@ -572,6 +723,9 @@ func TestResultSet(t *testing.T) {
 	}
 	assert.Equal(t, ErrorCode_SUCCEEDED, resultSet.GetErrorCode())
 	assert.Equal(t, int32(1000), resultSet.GetLatency())
+	assert.Equal(t, time.Millisecond, resultSet.Latency())
+	assert.Equal(t, &planDesc, resultSet.GetPlanDesc())
+	assert.Equal(t, false, resultSet.IsPartialSucceed())
 	assert.Equal(t, "test_err_msg", resultSet.GetErrorMsg())
 	assert.Equal(t, "test_space", resultSet.GetSpaceName())
 	assert.Equal(t, "test_comment", resultSet.GetComment())
@@ -588,6 +742,11 @@ func TestResultSet(t *testing.T) {
 	for i := 0; i < len(colNames); i++ {
 		assert.Equal(t, expectedColNames[i], colNames[i])
 	}
+	index, ok := resultSet.ColumnIndex("col2_vertex")
+	assert.True(t, ok)
+	assert.Equal(t, 2, index)
+	_, ok = resultSet.ColumnIndex("col2")
+	assert.False(t, ok)
 
 	record, err := resultSet.GetRowValuesByIndex(0)
 	if err != nil {
@@ -635,6 +794,57 @@ func TestResultSet(t *testing.T) {
 		resultSet.MakeDotGraph())
 }
 
+func TestColumnMeta(t *testing.T) {
+	null := nebula.NullType___NULL__
+	i := int64(1)
+	dataset := &nebula.DataSet{
+		ColumnNames: [][]byte{[]byte("int"), []byte("date"), []byte("null")},
+		Rows: []*nebula.Row{
+			{Values: []*nebula.Value{{NVal: &null}, {DVal: &nebula.Date{Year: 2021, Month: 1, Day: 1}}, {NVal: &null}}},
+			{Values: []*nebula.Value{{IVal: &i}, {NVal: &null}, {NVal: &null}}},
+		},
+	}
+	resp := &graph.ExecutionResponse{ErrorCode: nebula.ErrorCode_SUCCEEDED, Data: dataset}
+	resultSet, err := genResultSet(resp, testTimezone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []ColumnInfo{
+		{Name: "int", Type: "int"},
+		{Name: "date", Type: "date"},
+		{Name: "null", Type: ""},
+	}, resultSet.ColumnMeta())
+}
+
+func TestResultStream(t *testing.T) {
+	dataset := getDateset()
+	dataset.Rows = append(dataset.Rows, &nebula.Row{Values: []*nebula.Value{
+		setIVal(2), {SVal: []byte("value2")}, {VVal: getVertex("Bob", 1, 1)},
+		{EVal: getEdge("Bob", "Lily", 1)}, {PVal: getPath("Bob", 1)},
+	}})
+	resp := &graph.ExecutionResponse{ErrorCode: nebula.ErrorCode_SUCCEEDED, Data: dataset}
+	resultSet, err := genResultSet(resp, testTimezone)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stream := newResultStream(resultSet)
+	assert.Equal(t, resultSet.GetColNames(), stream.GetColNames())
+	for i := 1; i <= 2; i++ {
+		record, err := stream.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		val, _ := record.GetValueByColName("col0_int")
+		num, _ := val.AsInt()
+		assert.Equal(t, int64(i), num)
+		// Returned rows are released
+		assert.Nil(t, dataset.Rows[i-1])
+	}
+	_, err = stream.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
 func TestAsStringTable(t *testing.T) {
 	resp := &graph.ExecutionResponse{
 		nebula.ErrorCode_SUCCEEDED,
@@ -708,6 +918,46 @@ func TestIntVid(t *testing.T) {
 	assert.Equal(t, true, node.GetID().IsInt())
 }
 
+func TestResultSetMarshalJSON(t *testing.T) {
+	dataset := &nebula.DataSet{
+		ColumnNames: [][]byte{[]byte("vertex"), []byte("edge"), []byte("path"), []byte("nested")},
+		Rows: []*nebula.Row{{Values: []*nebula.Value{
+			{VVal: getVertex("Tom", 1, 1)},
+			{EVal: getEdge("Tom", "Lily", 1)},
+			{PVal: getPath("Tom", 1)},
+			{LVal: &nebula.NList{Values: []*nebula.Value{
+				{MVal: &nebula.NMap{Kvs: map[string]*nebula.Value{"e": {EVal: getEdge("Bob", "Tom", 0)}}}},
+				{DVal: &nebula.Date{Year: 2021, Month: 1, Day: 2}},
+			}}},
+		}}},
+	}
+	resp := &graph.ExecutionResponse{ErrorCode: nebula.ErrorCode_SUCCEEDED, LatencyInUs: 100, Data: dataset}
+	resultSet, err := genResultSet(resp, testTimezone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(resultSet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"columns":["vertex","edge","path","nested"],"error_code":0,"error_msg":"","latency_in_us":100,` +
+		`"rows":[[` +
+		`{"tags":[{"name":"tag0","props":{"prop0":0}}],"type":"vertex","vid":"Tom"},` +
+		`{"dst":"Lily","name":"classmate","props":{"prop0":0},"ranking":100,"src":"Tom","type":"edge"},` +
+		`{"nodes":[{"tags":[{"name":"tag0","props":{"prop0":0,"prop1":1,"prop2":2,"prop3":3,"prop4":4}},` +
+		`{"name":"tag1","props":{"prop0":0,"prop1":1,"prop2":2,"prop3":3,"prop4":4}},` +
+		`{"name":"tag2","props":{"prop0":0,"prop1":1,"prop2":2,"prop3":3,"prop4":4}}],"type":"vertex","vid":"Tom"},` +
+		`{"tags":[{"name":"tag0","props":{"prop0":0,"prop1":1,"prop2":2,"prop3":3,"prop4":4}},` +
+		`{"name":"tag1","props":{"prop0":0,"prop1":1,"prop2":2,"prop3":3,"prop4":4}},` +
+		`{"name":"tag2","props":{"prop0":0,"prop1":1,"prop2":2,"prop3":3,"prop4":4}}],"type":"vertex","vid":"vertex0"}],` +
+		`"relationships":[{"dst":"vertex0","name":"classmate","props":{"prop0":0,"prop1":1,"prop2":2,"prop3":3,"prop4":4},` +
+		`"ranking":100,"src":"Tom","type":"edge"}],"type":"path"},` +
+		`[{"kvs":{"e":{"dst":"Tom","name":"classmate","props":{},"ranking":100,"src":"Bob","type":"edge"}},"type":"map"},` +
+		`{"type":"date","value":"2021-01-02"}]` +
+		`]],"space_name":""}`
+	assert.Equal(t, expected, string(b))
+}
+
 func getVertex(vid string, tagNum int, propNum int) *nebula.Vertex {
 	var tags []*nebula.Tag
 	var vidVal = nebula.NewValue()
@@ -849,3 +1099,22 @@ func setIVal(ival int) *nebula.Value {
 	value.IVal = newNum
 	return value
 }
+
+func TestExecutionErrorIs(t *testing.T) {
+	newErr := func(code nebula.ErrorCode) error {
+		resSet, err := genResultSet(&graph.ExecutionResponse{ErrorCode: code}, testTimezone)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fmt.Errorf("failed to query, %w", checkResultSet(resSet))
+	}
+	err := newErr(nebula.ErrorCode_E_BAD_PERMISSION)
+	assert.True(t, errors.Is(err, ErrPermissionDenied))
+	assert.False(t, errors.Is(err, ErrSyntaxError))
+	assert.True(t, errors.Is(newErr(nebula.ErrorCode_E_SPACE_NOT_FOUND), ErrSpaceNotFound))
+	assert.True(t, errors.Is(newErr(nebula.ErrorCode_E_SYNTAX_ERROR), ErrSyntaxError))
+	err = newErr(nebula.ErrorCode_E_SEMANTIC_ERROR)
+	assert.False(t, errors.Is(err, ErrPermissionDenied))
+	assert.False(t, errors.Is(err, ErrSpaceNotFound))
+	assert.False(t, errors.Is(err, ErrSyntaxError))
+}
@@ -0,0 +1,128 @@
+/* Copyright (c) 2021 vesoft inc. All rights reserved.
+ *
+ * This source code is licensed under Apache 2.0 License,
+ * attached with Common Clause Condition 1.0, found in the LICENSES directory.
+ */
+
+package nebula_go
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+var (
+	timeType         = reflect.TypeOf(time.Time{})
+	valueWrapperType = reflect.TypeOf(ValueWrapper{})
+)
+
+// Scan copies the values of the record into the fields of the struct pointed to by dest.
+// A field is mapped to the column named by its `nebula:"colname"` tag, fields without the tag are skipped.
+//
+// Fields of kind string, bool, int, uint and float and of type time.Time and ValueWrapper are supported.
+// A float field also accepts an int value. A null value can only be scanned into a pointer field,
+// which is set to nil, the other pointer fields point to the scanned value.
+//
+// An error is returned if a tagged column does not exist in the record or if a value
+// does not match the type of its field.
+func (record Record) Scan(dest interface{}) error {
+	rv := reflect.ValueOf(dest)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("failed to scan: dest must be a non-nil pointer to a struct, got %T", dest)
+	}
+	rv = rv.Elem()
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		colName, ok := field.Tag.Lookup("nebula")
+		if !ok || colName == "-" {
+			continue
+		}
+		val, err := record.GetValueByColName(colName)
+		if err != nil {
+			return fmt.Errorf("failed to scan field %s: column %s does not exist", field.Name, colName)
+		}
+		if err = scanValue(val, rv.Field(i)); err != nil {
+			return fmt.Errorf("failed to scan column %s into field %s, %s", colName, field.Name, err.Error())
+		}
+	}
+	return nil
+}
+
+// Convert the value into the type of the field and set it
+func scanValue(val *ValueWrapper, field reflect.Value) error {
+	if !field.CanSet() {
+		return fmt.Errorf("field is not exported")
+	}
+	if field.Kind() == reflect.Ptr {
+		if val.IsNull() {
+			field.Set(reflect.Zero(field.Type()))
+			return nil
+		}
+		elem := reflect.New(field.Type().Elem())
+		if err := scanValue(val, elem.Elem()); err != nil {
+			return err
+		}
+		field.Set(elem)
+		return nil
+	}
+	mismatch := fmt.Errorf("value of type %s does not match field of type %s", val.GetType(), field.Type())
+	switch field.Type() {
+	case timeType:
+		t, err := val.AsGoTime()
+		if err != nil {
+			return mismatch
+		}
+		field.Set(reflect.ValueOf(t))
+		return nil
+	case valueWrapperType:
+		field.Set(reflect.ValueOf(*val))
+		return nil
+	}
+	switch field.Kind() {
+	case reflect.String:
+		s, err := val.AsString()
+		if err != nil {
+			return mismatch
+		}
+		field.SetString(s)
+	case reflect.Bool:
+		b, err := val.AsBool()
+		if err != nil {
+			return mismatch
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := val.AsInt()
+		if err != nil {
+			return mismatch
+		}
+		if field.OverflowInt(n) {
+			return fmt.Errorf("value %d overflows field of type %s", n, field.Type())
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := val.AsInt()
+		if err != nil {
+			return mismatch
+		}
+		if n < 0 || field.OverflowUint(uint64(n)) {
+			return fmt.Errorf("value %d overflows field of type %s", n, field.Type())
+		}
+		field.SetUint(uint64(n))
+	case reflect.Float32, reflect.Float64:
+		f, err := val.AsFloat()
+		if err != nil {
+			n, err := val.AsInt()
+			if err != nil {
+				return mismatch
+			}
+			f = float64(n)
+		}
+		field.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field of type %s", field.Type())
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+/* Copyright (c) 2021 vesoft inc. All rights reserved.
+ *
+ * This source code is licensed under Apache 2.0 License,
+ * attached with Common Clause Condition 1.0, found in the LICENSES directory.
+ */
+
+package nebula_go
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vesoft-inc/nebula-go/v2/nebula"
+	"github.com/vesoft-inc/nebula-go/v2/nebula/graph"
+)
+
+func TestRecordScan(t *testing.T) {
+	null := nebula.NullType___NULL__
+	fval := 1.5
+	bval := true
+	dataset := &nebula.DataSet{
+		ColumnNames: [][]byte{
+			[]byte("name"), []byte("age"), []byte("score"), []byte("married"),
+			[]byte("birthday"), []byte("nickname"), []byte("rank"),
+		},
+		Rows: []*nebula.Row{{Values: []*nebula.Value{
+			{SVal: []byte("Tom")}, setIVal(20), {FVal: &fval}, {BVal: &bval},
+			{DtVal: &nebula.DateTime{Year: 2001, Month: 2, Day: 3}}, {NVal: &null}, setIVal(3),
+		}}},
+	}
+	resp := &graph.ExecutionResponse{ErrorCode: nebula.ErrorCode_SUCCEEDED, Data: dataset}
+	resultSet, err := genResultSet(resp, testTimezone)
+	if err != nil {
+		t.Fatal(err)
+	}
+	record, err := resultSet.GetRowValuesByIndex(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var person struct {
+		Name     string    `nebula:"name"`
+		Age      int8      `nebula:"age"`
+		Score    float64   `nebula:"score"`
+		Married  bool      `nebula:"married"`
+		Birthday time.Time `nebula:"birthday"`
+		Nickname *string   `nebula:"nickname"`
+		Rank     *float32  `nebula:"rank"`
+		Ignored  string
+	}
+	if err = record.Scan(&person); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "Tom", person.Name)
+	assert.Equal(t, int8(20), person.Age)
+	assert.Equal(t, 1.5, person.Score)
+	assert.Equal(t, true, person.Married)
+	assert.True(t, time.Date(2001, 2, 3, 0, 0, 0, 0, time.UTC).Equal(person.Birthday))
+	assert.Nil(t, person.Nickname)
+	assert.Equal(t, float32(3), *person.Rank)
+
+	// Type mismatch
+	var mismatch struct {
+		Name int64 `nebula:"name"`
+	}
+	assert.NotNil(t, record.Scan(&mismatch))
+
+	// Null into a non-pointer field
+	var notNullable struct {
+		Nickname string `nebula:"nickname"`
+	}
+	assert.NotNil(t, record.Scan(&notNullable))
+
+	// Missing column
+	var missing struct {
+		Address string `nebula:"address"`
+	}
+	assert.NotNil(t, record.Scan(&missing))
+
+	assert.NotNil(t, record.Scan(person))
+}
@@ -0,0 +1,183 @@
+/* Copyright (c) 2021 vesoft inc. All rights reserved.
+ *
+ * This source code is licensed under Apache 2.0 License,
+ * attached with Common Clause Condition 1.0, found in the LICENSES directory.
+ */
+
+package nebula_go
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// ExecuteFile executes the semicolon separated statements of the given nGQL file in order,
+// see ExecuteReader.
+func (session *Session) ExecuteFile(path string, continueOnError bool) ([]*ResultSet, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open nGQL file, error: %w", err)
+	}
+	defer file.Close()
+	return session.ExecuteReader(file, continueOnError)
+}
+
+// ExecuteReader executes the semicolon separated statements read from r in order,
+// the statements are split as by SplitStatements.
+//
+// Unless continueOnError is set, the execution stops at the first statement which failed to be sent
+// or was not succeeded, the results of the statements executed before are returned with an error
+// holding the index of the failed statement. If continueOnError is set, all statements are executed
+// and the results and the error are returned as by ExecuteBatch.
+func (session *Session) ExecuteReader(r io.Reader, continueOnError bool) ([]*ResultSet, error) {
+	script, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read nGQL statements, error: %w", err)
+	}
+	stmts := SplitStatements(string(script))
+	if continueOnError {
+		return session.ExecuteBatch(stmts)
+	}
+	results := make([]*ResultSet, 0, len(stmts))
+	for i, stmt := range stmts {
+		resSet, err := session.Execute(stmt)
+		if err == nil {
+			err = checkResultSet(resSet)
+		}
+		if err != nil {
+			return results, fmt.Errorf("failed to execute statement %d, %w", i, err)
+		}
+		results = append(results, resSet)
+	}
+	return results, nil
+}
+
+// SplitStatements splits the script into statements on the semicolons outside of quoted strings
+// and comments. Strings are quoted by single or double quotes or backticks, and may contain
+// quotes escaped by a backslash. Comments start with # or // and end at the end of the line,
+// or are enclosed in /* and */. The comments are removed, the statements are trimmed and empty
+// statements are skipped.
+func SplitStatements(script string) []string {
+	var stmts []string
+	var stmt strings.Builder
+	flush := func() {
+		if s := strings.TrimSpace(stmt.String()); s != "" {
+			stmts = append(stmts, s)
+		}
+		stmt.Reset()
+	}
+	for i := 0; i < len(script); i++ {
+		c := script[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			// Copy the quoted string up to the closing quote or the end of the script
+			end := i + 1
+			for ; end < len(script) && script[end] != c; end++ {
+				if script[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(script) {
+				end = len(script) - 1
+			}
+			stmt.WriteString(script[i : end+1])
+			i = end
+		case c == '#' || strings.HasPrefix(script[i:], "//"):
+			end := strings.IndexByte(script[i:], '\n')
+			if end < 0 {
+				i = len(script)
+				break
+			}
+			// Keep the newline to separate the tokens around the comment
+			i += end - 1
+		case strings.HasPrefix(script[i:], "/*"):
+			end := strings.Index(script[i+2:], "*/")
+			if end < 0 {
+				i = len(script)
+				break
+			}
+			stmt.WriteByte(' ')
+			i += end + 3
+		case c == ';':
+			flush()
+		default:
+			stmt.WriteByte(c)
+		}
+	}
+	flush()
+	return stmts
+}
+
+// ValidateStatement checks the statement for the structural errors which make it fail to be parsed
+// by the server: an empty statement, a string or a backtick-quoted identifier which is not closed,
+// an unclosed block comment and unbalanced parentheses, brackets or braces. It is not a parser,
+// a statement which passes the check may still be rejected by the server. Quotes and comments
+// are recognized as in SplitStatements.
+func ValidateStatement(stmt string) error {
+	var open []int // the offsets of the unclosed parentheses, brackets and braces
+	empty := true
+	for i := 0; i < len(stmt); i++ {
+		c := stmt[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			end := i + 1
+			for ; end < len(stmt) && stmt[end] != c; end++ {
+				if stmt[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(stmt) {
+				return fmt.Errorf("invalid statement: unterminated %c quote at offset %d", c, i)
+			}
+			empty = false
+			i = end
+		case c == '#' || strings.HasPrefix(stmt[i:], "//"):
+			end := strings.IndexByte(stmt[i:], '\n')
+			if end < 0 {
+				i = len(stmt)
+				break
+			}
+			i += end
+		case strings.HasPrefix(stmt[i:], "/*"):
+			end := strings.Index(stmt[i+2:], "*/")
+			if end < 0 {
+				return fmt.Errorf("invalid statement: unterminated comment at offset %d", i)
+			}
+			i += end + 3
+		case c == '(' || c == '[' || c == '{':
+			empty = false
+			open = append(open, i)
+		case c == ')' || c == ']' || c == '}':
+			empty = false
+			if len(open) == 0 || closingBracket(stmt[open[len(open)-1]]) != c {
+				return fmt.Errorf("invalid statement: unbalanced %c at offset %d", c, i)
+			}
+			open = open[:len(open)-1]
+		case c != ';' && !unicode.IsSpace(rune(c)):
+			empty = false
+		}
+	}
+	if len(open) > 0 {
+		i := open[len(open)-1]
+		return fmt.Errorf("invalid statement: unclosed %c at offset %d", stmt[i], i)
+	}
+	if empty {
+		return fmt.Errorf("invalid statement: the statement is empty")
+	}
+	return nil
+}
+
+// Return the bracket closing the opening one
+func closingBracket(c byte) byte {
+	switch c {
+	case '(':
+		return ')'
+	case '[':
+		return ']'
+	}
+	return '}'
+}
@@ -0,0 +1,100 @@
+/* Copyright (c) 2021 vesoft inc. All rights reserved.
+ *
+ * This source code is licensed under Apache 2.0 License,
+ * attached with Common Clause Condition 1.0, found in the LICENSES directory.
+ */
+
+package nebula_go
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vesoft-inc/nebula-go/v2/fake"
+	"github.com/vesoft-inc/nebula-go/v2/nebula"
+)
+
+func TestSplitStatements(t *testing.T) {
+	script := `# create the schema
+CREATE SPACE IF NOT EXISTS test(vid_type=FIXED_STRING(8)); USE test;
+CREATE TAG person(name string); // trailing comment; not a statement
+/* block; comment */ INSERT VERTEX person(name) VALUES "a":("x;y"), "b":('it\'s; "quoted"');
+;;
+MATCH (v)--(v2) RETURN v2 /* unterminated`
+	stmts := SplitStatements(script)
+	assert.Equal(t, []string{
+		"CREATE SPACE IF NOT EXISTS test(vid_type=FIXED_STRING(8))",
+		"USE test",
+		"CREATE TAG person(name string)",
+		`INSERT VERTEX person(name) VALUES "a":("x;y"), "b":('it\'s; "quoted"')`,
+		"MATCH (v)--(v2) RETURN v2",
+	}, stmts)
+
+	assert.Nil(t, SplitStatements(" # only a comment\n ; "))
+	assert.Equal(t, []string{"YIELD 'unterminated;"}, SplitStatements("YIELD 'unterminated;"))
+}
+
+func TestExecuteReader(t *testing.T) {
+	service := fake.NewGraphService()
+	service.SetError("CREATE TAG person(name string)", nebula.ErrorCode_E_EXECUTION_ERROR, "Existed!")
+	session, closeSession := newFakeSession(t, service)
+	defer closeSession()
+	script := "YIELD 1; CREATE TAG person(name string); YIELD 2;"
+
+	// The execution stops at the failed statement
+	results, err := session.ExecuteReader(strings.NewReader(script), false)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "failed to execute statement 1")
+	assert.Len(t, results, 1)
+	assert.Equal(t, []string{"YIELD 1", "CREATE TAG person(name string)"}, service.Executed())
+
+	// All statements are executed
+	results, err = session.ExecuteReader(strings.NewReader(script), true)
+	var batchErr *BatchError
+	assert.True(t, errors.As(err, &batchErr))
+	assert.Equal(t, []int{1}, batchErr.FailedIndexes())
+	assert.Len(t, results, 3)
+	assert.True(t, results[2].IsSucceed())
+	assert.Equal(t, []string{"YIELD 1", "CREATE TAG person(name string)",
+		"YIELD 1", "CREATE TAG person(name string)", "YIELD 2"}, service.Executed())
+}
+
+func TestValidateStatement(t *testing.T) {
+	for _, stmt := range []string{
+		"YIELD 1",
+		"MATCH (v:player{name: \"Tim\"})-[e:follow*1..2]->(v2) RETURN [v2.age, (1 + 2)]",
+		"INSERT VERTEX player(name) VALUES \"p1\":(\"a) \\\" ]\"), `p(2`:('}')",
+		"YIELD 1 # unbalanced ( in a comment\n",
+		"YIELD 1 /* ( */; YIELD 2;",
+	} {
+		assert.Nil(t, ValidateStatement(stmt), stmt)
+	}
+	for _, stmt := range []string{
+		"",
+		" \n\t; ",
+		"# only a comment",
+		"YIELD \"unterminated",
+		"YIELD 'it\\'s",
+		"USE `test",
+		"YIELD 1 /* unterminated",
+		"MATCH (v RETURN v",
+		"YIELD [1, 2)",
+		"YIELD 1)",
+		"GO FROM \"p1\" OVER follow WHERE {",
+	} {
+		assert.NotNil(t, ValidateStatement(stmt), stmt)
+	}
+
+	service := fake.NewGraphService()
+	session, closeSession := newFakeSession(t, service, func(conf *PoolConfig) {
+		conf.ValidateBeforeSend = true
+	})
+	defer closeSession()
+	_, err := session.Execute("MATCH (v RETURN v")
+	assert.NotNil(t, err)
+	_, err = session.Execute("YIELD 1")
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"YIELD 1"}, service.Executed())
+}
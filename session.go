@@ -7,7 +7,13 @@
 package nebula_go
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/facebook/fbthrift/thrift/lib/go/thrift"
 	"github.com/vesoft-inc/nebula-go/v2/nebula"
@@ -19,63 +25,658 @@ type timezoneInfo struct {
 	name   []byte
 }
 
+// Session is safe for concurrent use by multiple goroutines. Its connection handles one request
+// at a time, so the queries of the goroutines sharing a session are serialized: a query waits for
+// the running one to complete, and Release waits for the running query too. Use a session per
+// goroutine to execute queries in parallel.
 type Session struct {
+	// Serializes the use of the connection, a response read by another request would be corrupted
+	mu         sync.Mutex
 	sessionID  int64
 	connection *connection
 	connPool   *ConnectionPool
-	log        Logger
+	log        StructuredLogger
 	timezoneInfo
+	// credentials kept to re-authenticate when the session expired, only set if enabled by the pool config
+	credentials CredentialProvider
+	reauthCount int
+	space       string // the space of the last succeeded query
+	label       string // the label the hosts of the session must carry, empty if any host can be used
+	// the VID types of the spaces cached by VIDType
+	vidTypes map[string]VIDType
 }
 
-// unsupported
-// func (session *Session) ExecuteJson(stmt string) (*graph.ExecutionResponse, error) {
-// 	return session.graph.ExecuteJson(session.sessionID, []byte(stmt))
-// }
+// ExecuteJson returns the result of given query in JSON, see ExecuteJsonWithContext
+func (session *Session) ExecuteJson(stmt string) ([]byte, error) {
+	return session.ExecuteJsonWithContext(context.Background(), stmt)
+}
 
-// Execute returns the result of given query as a ResultSet
+// ExecuteJsonWithContext returns the result of given query in JSON, the errors reported by the
+// server are in the JSON rather than returned as an error.
+// The query is aborted when ctx is cancelled or its deadline expires, in which case the connection
+// is reopened and the returned error wraps ctx.Err(). The checks, limits and instrumentation of the
+// pool config apply as in ExecuteWithContext, and the session is reconnected if its connection was
+// closed, but the query is neither retried on a leader change, re-authenticated nor failed over.
+// The servers released before ExecuteJson was implemented fail the query with a transport error.
+func (session *Session) ExecuteJsonWithContext(ctx context.Context, stmt string) ([]byte, error) {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if session.connPool == nil {
+		resp, _, err := session.executeJsonWithReconnect(ctx, stmt)
+		return resp, err
+	}
+	var resp []byte
+	err := session.runQuery(ctx, stmt, func(ctx context.Context) (time.Duration, error, error) {
+		var parsed jsonResponse
+		var err error
+		if resp, parsed, err = session.executeJsonWithReconnect(ctx, stmt); err != nil {
+			return 0, nil, err
+		}
+		return parsed.latency(), parsed.err(), nil
+	})
+	return resp, err
+}
+
+// Execute the query in JSON and reconnect if the connection was closed, see executeWithReconnect.
+// The space of the session is updated as by genResultSet.
+func (session *Session) executeJsonWithReconnect(ctx context.Context, stmt string) ([]byte, jsonResponse, error) {
+	var resp []byte
+	err := session.withReconnect(func() (err error) {
+		resp, err = session.connection.executeJsonWithContext(ctx, session.sessionID, stmt)
+		return err
+	})
+	if err != nil {
+		return nil, jsonResponse{}, err
+	}
+	parsed := parseJsonResponse(resp)
+	if space, ok := parsed.space(); ok {
+		session.space = space
+	}
+	return resp, parsed, nil
+}
+
+// Return ctx with the QueryTimeout of the pool config as its deadline if it has none
+func (session *Session) withQueryTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if session.connPool == nil || session.connPool.conf.QueryTimeout <= 0 {
+		return ctx, func() {}
+	}
+	if _, ok := ctx.Deadline(); ok {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, session.connPool.conf.QueryTimeout)
+}
+
+// Execute returns the result of given query as a ResultSet.
+// The response of the server holds a single result: if the query holds several statements
+// separated by semicolons, they are executed in one request and only the result of the last one
+// is returned, the execution stopping at the first failed statement. To get the result of each
+// statement, pass them to ExecuteBatch, or a script to ExecuteReader which splits its statements.
 func (session *Session) Execute(stmt string) (*ResultSet, error) {
-	if session.connection == nil {
-		return nil, fmt.Errorf("failed to execute: Session has been released")
+	return session.ExecuteWithContext(context.Background(), stmt)
+}
+
+// ExecuteWithContext returns the result of given query as a ResultSet.
+// The query is aborted when ctx is cancelled or its deadline expires, in which case
+// the returned error wraps ctx.Err().
+//
+// If ReauthOnSessionExpired is set in the pool config and the server reports the session
+// expired, the session re-authenticates with its credentials and executes the query once more.
+//
+// If QueryTimeout is set in the pool config and ctx has no deadline, the query is aborted
+// after QueryTimeout as if ctx had this deadline.
+//
+// If LeaderChangeRetries is set in the pool config, the query is executed again after
+// LeaderChangeRetryDelay when the server reports a leader change or a transient storage error,
+// see ResultSet.GetRetryCount.
+//
+// If FailoverReadOnly is set in the pool config, a query classified as read-only is failed over
+// as with ExecuteIdempotent.
+func (session *Session) ExecuteWithContext(ctx context.Context, stmt string) (*ResultSet, error) {
+	return session.executeWithContext(ctx, stmt, false)
+}
+
+// ExecuteIdempotent returns the result of given query as a ResultSet.
+// The query must be idempotent: if FailoverRetries is set in the pool config and the query
+// fails with a transport error, it is executed again on a connection to another host,
+// see ResultSet.GetHostAddress. Queries with side effects should use Execute instead,
+// as the failed query may have been executed by the server before the transport failed.
+func (session *Session) ExecuteIdempotent(stmt string) (*ResultSet, error) {
+	return session.executeWithContext(context.Background(), stmt, true)
+}
+
+func (session *Session) executeWithContext(ctx context.Context, stmt string, idempotent bool) (*ResultSet, error) {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if session.connPool == nil {
+		return session.executeWithReauth(ctx, stmt)
 	}
-	resp, err := session.connection.execute(session.sessionID, stmt)
-	if err == nil {
-		resSet, err := genResultSet(resp, session.timezoneInfo)
+	var resSet *ResultSet
+	err := session.runQuery(ctx, stmt, func(ctx context.Context) (time.Duration, error, error) {
+		var err error
+		resSet, err = session.executeWithRetry(ctx, stmt)
+		if idempotent || session.isReadOnly(stmt) {
+			resSet, err = session.executeWithFailover(ctx, stmt, resSet, err)
+		}
 		if err != nil {
+			return 0, nil, err
+		}
+		resSet.hostAddress = session.connection.severAddress
+		return resSet.Latency(), checkResultSet(resSet), nil
+	})
+	return resSet, err
+}
+
+// Run the query with the checks and the instrumentation of the pool config: the statement is checked
+// against MaxStmtBytes and ValidateBeforeSend, QueryTimeout and MaxTotalResultBytes apply, and the query
+// is reported to the hooks, the tracer and the slow query log. run returns the latency reported by the
+// server, the error reported by the server in the result and the error of the execution.
+// The caller should hold the lock of the session, which must be created by a pool.
+func (session *Session) runQuery(ctx context.Context, stmt string,
+	run func(ctx context.Context) (latency time.Duration, resultErr error, err error)) error {
+	conf := &session.connPool.conf
+	if maxStmt := conf.MaxStmtBytes; maxStmt > 0 && len(stmt) > maxStmt {
+		return fmt.Errorf("failed to execute: the statement of %d bytes exceeds the max statement size of %d bytes",
+			len(stmt), maxStmt)
+	}
+	if conf.ValidateBeforeSend {
+		if err := ValidateStatement(stmt); err != nil {
+			return fmt.Errorf("failed to execute: %w", err)
+		}
+	}
+	ctx, cancel := session.withQueryTimeout(ctx)
+	defer cancel()
+	release, err := session.connPool.reserveResult(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+	conf.Hooks.queryStart(stmt)
+	span := conf.startQuerySpan(ctx, stmt)
+	start := time.Now()
+	serverLatency, resultErr, err := run(ctx)
+	if err == nil {
+		if threshold := conf.SlowQueryThreshold; threshold > 0 && serverLatency >= threshold {
+			session.log.Warn("slow query", "host", session.connection.severAddress, "latency", serverLatency,
+				"statement", truncateStmt(conf.redactStmt(stmt)))
+		}
+	}
+	if conf.Hooks.OnQueryEnd != nil || span != nil {
+		latency := time.Since(start)
+		hookErr := err
+		if err == nil {
+			hookErr = resultErr
+		}
+		if conf.Hooks.OnQueryEnd != nil {
+			conf.Hooks.OnQueryEnd(stmt, latency, hookErr)
+		}
+		if span != nil {
+			info := QueryTrace{Space: session.space, Latency: latency, Err: hookErr}
+			if session.connection != nil {
+				info.Host = session.connection.severAddress.String()
+			}
+			span.End(info)
+		}
+	}
+	return err
+}
+
+// The max size of a statement in the logs
+const maxLoggedStmtBytes = 512
+
+// Truncate the statement to maxLoggedStmtBytes for the logs
+func truncateStmt(stmt string) string {
+	if len(stmt) <= maxLoggedStmtBytes {
+		return stmt
+	}
+	return fmt.Sprintf("%s... (%d bytes truncated)", stmt[:maxLoggedStmtBytes], len(stmt)-maxLoggedStmtBytes)
+}
+
+// Execute the query and retry on a leader change if it is enabled
+func (session *Session) executeWithRetry(ctx context.Context, stmt string) (*ResultSet, error) {
+	resSet, err := session.executeWithReauth(ctx, stmt)
+	conf := session.connPool.conf
+	for retry := 1; err == nil && retry <= conf.LeaderChangeRetries && isRetriable(resSet); retry++ {
+		session.log.Warn("query failed, retrying", "session", session.sessionID, "code", resSet.GetErrorCode(),
+			"retry", retry, "max_retries", conf.LeaderChangeRetries)
+		timer := time.NewTimer(conf.LeaderChangeRetryDelay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("failed to execute: %w", ctx.Err())
+		}
+		resSet, err = session.executeWithReauth(ctx, stmt)
+		if err == nil {
+			resSet.retryCount = retry
+		}
+	}
+	return resSet, err
+}
+
+// Execute the query again on another host while it fails with a transport error and failover is enabled
+func (session *Session) executeWithFailover(ctx context.Context, stmt string,
+	resSet *ResultSet, err error) (*ResultSet, error) {
+	retries := session.connPool.conf.FailoverRetries
+	for retry := 1; err != nil && retry <= retries && isTransportError(err) && ctx.Err() == nil; retry++ {
+		failedHost := session.connection.severAddress
+		if _err := session.failover(); _err != nil {
+			session.log.Error("failed to fail over", "session", session.sessionID, "host", failedHost, "error", _err)
 			return nil, err
 		}
-		return resSet, nil
+		session.log.Warn("query failed, retrying on another host", "session", session.sessionID, "host", failedHost,
+			"error", err, "retry", retry, "max_retries", retries, "new_host", session.connection.severAddress)
+		resSet, err = session.executeWithRetry(ctx, stmt)
+	}
+	return resSet, err
+}
+
+// Replace the connection of the session with a connection to another host
+func (session *Session) failover() error {
+	exclude, err := session.connPool.hostsWithoutLabel(session.label)
+	if err != nil {
+		return err
+	}
+	newConnection, err := session.connPool.getIdleConn(append(exclude, session.connection.severAddress)...)
+	if err != nil {
+		return err
+	}
+	session.connPool.release(session.connection)
+	session.connection = newConnection
+	return session.useConfiguredSpace()
+}
+
+// Check if the query is read-only and FailoverReadOnly is set in the pool config
+func (session *Session) isReadOnly(stmt string) bool {
+	conf := session.connPool.conf
+	if !conf.FailoverReadOnly {
+		return false
+	}
+	if conf.ReadOnlyClassifier != nil {
+		return conf.ReadOnlyClassifier(stmt)
+	}
+	return IsReadOnlyStmt(stmt)
+}
+
+// The leading keywords of the read-only statements
+var readOnlyKeywords = map[string]bool{
+	"MATCH":    true,
+	"GO":       true,
+	"FETCH":    true,
+	"LOOKUP":   true,
+	"YIELD":    true,
+	"FIND":     true,
+	"GET":      true,
+	"SHOW":     true,
+	"DESCRIBE": true,
+	"DESC":     true,
+}
+
+// IsReadOnlyStmt reports whether the statement is read-only by its leading keyword, which is one of
+// MATCH, GO, FETCH, LOOKUP, YIELD, FIND (PATH), GET (SUBGRAPH), SHOW, DESCRIBE and DESC.
+// The statements separated by ";" and the clauses piped with "|" must all be read-only, e.g.
+// "GO FROM 1 OVER follow | DELETE VERTEX $-.id" is not. The classification errs on the side of
+// not read-only, e.g. for the statements assigned to a variable or starting with a comment.
+func IsReadOnlyStmt(stmt string) bool {
+	parts := strings.FieldsFunc(stmt, func(r rune) bool {
+		return r == ';' || r == '|'
+	})
+	readOnly := false
+	for _, part := range parts {
+		fields := strings.Fields(part)
+		if len(fields) == 0 {
+			continue
+		}
+		if !readOnlyKeywords[strings.ToUpper(fields[0])] {
+			return false
+		}
+		readOnly = true
+	}
+	return readOnly
+}
+
+// Check if the query failed to be sent or its response failed to be received
+func isTransportError(err error) bool {
+	if _, ok := err.(thrift.TransportException); ok {
+		return true
+	}
+	var transErr *TransportError
+	return errors.As(err, &transErr)
+}
+
+// Execute the query and re-authenticate if the session expired and it is enabled
+func (session *Session) executeWithReauth(ctx context.Context, stmt string) (*ResultSet, error) {
+	resSet, err := session.executeWithReconnect(ctx, stmt)
+	if err != nil || session.connPool == nil || !session.connPool.conf.ReauthOnSessionExpired ||
+		!isSessionExpired(resSet) {
+		return resSet, err
+	}
+	if err = session.reauthenticate(); err != nil {
+		return nil, err
+	}
+	return session.executeWithReconnect(ctx, stmt)
+}
+
+// Execute the query and reconnect if the transport is closed
+func (session *Session) executeWithReconnect(ctx context.Context, stmt string) (*ResultSet, error) {
+	var resp *graph.ExecutionResponse
+	err := session.withReconnect(func() (err error) {
+		resp, err = session.connection.executeWithContext(ctx, session.sessionID, stmt)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return session.genResultSet(resp)
+}
+
+// Make the request on the connection of the session, the session is reconnected and the request
+// is made again if the connection was closed
+func (session *Session) withReconnect(call func() error) error {
+	if session.connection == nil {
+		return fmt.Errorf("failed to execute: Session has been released")
+	}
+	err := call()
+	if err == nil {
+		return nil
 	}
 	// Reconnect only if the tranport is closed
 	err2, ok := err.(thrift.TransportException)
 	if !ok {
-		return nil, err
+		return err
 	}
 	if err2.TypeID() == thrift.END_OF_FILE {
 		_err := session.reConnect()
 		if _err != nil {
-			session.log.Error(fmt.Sprintf("Failed to reconnect, %s", _err.Error()))
-			return nil, _err
+			session.log.Error("failed to reconnect", "session", session.sessionID, "error", _err)
+			return _err
+		}
+		session.log.Info("reconnected", "session", session.sessionID, "host", session.connection.severAddress)
+		if session.connPool.conf.DisableAutoReopen {
+			// The query may have been executed before the connection was closed
+			return fmt.Errorf("failed to execute: the connection was closed, the query is not executed again: %w", err2)
 		}
-		session.log.Info(fmt.Sprintf("Successfully reconnect to host: %s, port: %d",
-			session.connection.severAddress.Host, session.connection.severAddress.Port))
 		// Execute with the new connetion
-		resp, err := session.connection.execute(session.sessionID, stmt)
-		if err != nil {
-			return nil, err
+		return call()
+	} else { // No need to reconnect
+		session.log.Error("query failed with a transport error", "session", session.sessionID, "error", err2)
+		return err2
+	}
+}
+
+// Generate the result set of the response and track the current space
+func (session *Session) genResultSet(resp *graph.ExecutionResponse) (*ResultSet, error) {
+	resSet, err := genResultSet(resp, session.timezoneInfo)
+	if err != nil {
+		return nil, err
+	}
+	if resSet.IsSucceed() {
+		session.space = resSet.GetSpaceName()
+	}
+	return resSet, nil
+}
+
+// Ping checks the connection hold by session.
+// An error is returned if the check query failed to be sent or was rejected by the server.
+func (session *Session) Ping() error {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if session.connection == nil {
+		return fmt.Errorf("failed to ping: Session has been released")
+	}
+	resp, err := session.connection.execute(session.sessionID, session.connection.healthCheckStmt())
+	if err != nil {
+		return fmt.Errorf("failed to ping, error: %s", err.Error())
+	}
+	if IsError(resp) {
+		return &ExecutionError{
+			Msg:       fmt.Sprintf("failed to ping, ErrorCode: %v, ErrorMsg: %s", resp.GetErrorCode(), resp.GetErrorMsg()),
+			Code:      ErrorCode(resp.GetErrorCode()),
+			ServerMsg: string(resp.GetErrorMsg()),
+		}
+	}
+	return nil
+}
+
+// ExecuteWithTimeout returns the result of given query as a ResultSet.
+// The query is aborted and the connection is reopened if no response is received within
+// timeoutMs milliseconds, the returned error wraps context.DeadlineExceeded in this case.
+// A timeoutMs <= 0 means no timeout other than the QueryTimeout of the pool config.
+func (session *Session) ExecuteWithTimeout(stmt string, timeoutMs int64) (*ResultSet, error) {
+	if timeoutMs <= 0 {
+		return session.Execute(stmt)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(timeoutMs)*time.Millisecond)
+	defer cancel()
+	resSet, err := session.ExecuteWithContext(ctx, stmt)
+	if err != nil && errors.Is(err, context.DeadlineExceeded) {
+		return nil, fmt.Errorf("failed to execute: query timed out after %d ms: %w", timeoutMs, context.DeadlineExceeded)
+	}
+	return resSet, err
+}
+
+// ExecuteAndCheck returns the result of given query as a ResultSet.
+// Unlike Execute, an *ExecutionError holding the error code and message of the server is returned
+// if the query is not succeeded, it can be matched with errors.Is, e.g. against ErrPermissionDenied.
+func (session *Session) ExecuteAndCheck(stmt string) (*ResultSet, error) {
+	resSet, err := session.Execute(stmt)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkResultSet(resSet); err != nil {
+		return nil, err
+	}
+	return resSet, nil
+}
+
+// Return an *ExecutionError holding the error code and message of the server if the query is not succeeded
+func checkResultSet(resSet *ResultSet) error {
+	if resSet.IsSucceed() {
+		return nil
+	}
+	return newExecutionError("failed to execute, ", resSet)
+}
+
+// Create the error of a result set which is not succeeded, the message starts with prefix
+func newExecutionError(prefix string, resSet *ResultSet) *ExecutionError {
+	return &ExecutionError{
+		Msg:       fmt.Sprintf("%sErrorCode: %v, ErrorMsg: %s", prefix, resSet.GetErrorCode(), resSet.GetErrorMsg()),
+		Code:      resSet.GetErrorCode(),
+		ServerMsg: resSet.GetErrorMsg(),
+	}
+}
+
+// ExecuteStream returns the result of given query as a ResultStream which yields one record at a time.
+// An error is returned if the query is not succeeded.
+// See ResultStream for the memory usage, the server does not page the result.
+func (session *Session) ExecuteStream(stmt string) (*ResultStream, error) {
+	resSet, err := session.ExecuteAndCheck(stmt)
+	if err != nil {
+		return nil, err
+	}
+	return newResultStream(resSet), nil
+}
+
+// ExecuteBatch executes the statements in order on the connection of the session.
+// The statements are sent one after another, which saves acquiring a session per statement
+// but still costs a round trip each.
+//
+// All statements are executed even if some of them fail. The ResultSet of each statement is
+// returned at its index, it is nil if the statement failed to be sent. If any statement failed
+// to be sent or was not succeeded, a *BatchError holding the error of each statement is returned.
+func (session *Session) ExecuteBatch(stmts []string) ([]*ResultSet, error) {
+	results := make([]*ResultSet, len(stmts))
+	errs := make([]error, len(stmts))
+	failed := false
+	for i, stmt := range stmts {
+		resSet, err := session.Execute(stmt)
+		results[i] = resSet
+		if err == nil && !resSet.IsSucceed() {
+			err = newExecutionError("", resSet)
 		}
-		resSet, err := genResultSet(resp, session.timezoneInfo)
 		if err != nil {
-			return nil, err
+			errs[i] = err
+			failed = true
 		}
-		return resSet, nil
-	} else { // No need to reconnect
-		session.log.Error(fmt.Sprintf("Error info: %s", err2.Error()))
-		return nil, err2
 	}
+	if failed {
+		return results, &BatchError{Errors: errs}
+	}
+	return results, nil
+}
+
+// SessionID returns the ID of the session on the server, as listed by SHOW SESSIONS.
+// It changes when the session re-authenticates, see ReauthCount.
+func (session *Session) SessionID() int64 {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	return session.sessionID
+}
+
+// CurrentSpace returns the space the session is in after its last succeeded query,
+// it is empty if no space was used yet
+func (session *Session) CurrentSpace() string {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	return session.space
+}
+
+// RemoteAddr returns the address of the graph service the socket of the session is connected to,
+// e.g. to tell which instance behind a virtual IP serves the session. It is the resolved address
+// of the host the session was created on, and changes when the session is reconnected or failed over.
+// An error is returned if the session was released or its connection is not backed by a socket,
+// e.g. if it is created by the GraphServiceFactory of the pool config.
+func (session *Session) RemoteAddr() (net.Addr, error) {
+	conn, err := session.netConn()
+	if err != nil {
+		return nil, err
+	}
+	return conn.RemoteAddr(), nil
+}
+
+// LocalAddr returns the local address of the socket of the session, see RemoteAddr
+func (session *Session) LocalAddr() (net.Addr, error) {
+	conn, err := session.netConn()
+	if err != nil {
+		return nil, err
+	}
+	return conn.LocalAddr(), nil
+}
+
+// Return the net.Conn of the connection of the session
+func (session *Session) netConn() (net.Conn, error) {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if session.connection == nil {
+		return nil, fmt.Errorf("failed to get address: Session has been released")
+	}
+	conn, err := session.connection.netConn()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get address: %w", err)
+	}
+	return conn, nil
+}
+
+// Use switches the session to the given space, see CurrentSpace.
+// An error is returned without executing the statement if the name is empty or contains a backtick,
+// which cannot be quoted. An error wrapping the *ExecutionError of the server is returned if the space
+// cannot be used, e.g. if it does not exist.
+func (session *Session) Use(space string) error {
+	if space == "" {
+		return fmt.Errorf("failed to use space: the space name is empty")
+	}
+	if strings.ContainsAny(space, "`\n") {
+		return fmt.Errorf("failed to use space %q: the space name contains a backtick or a line break", space)
+	}
+	if _, err := session.ExecuteAndCheck(fmt.Sprintf("USE `%s`", space)); err != nil {
+		return fmt.Errorf("failed to use space %s, %w", space, err)
+	}
+	return nil
+}
+
+// Use the SpaceName of the pool config if set, the statement is executed on the connection directly
+// so that a failure does not reconnect the session again
+func (session *Session) useConfiguredSpace() error {
+	if session.connPool == nil || session.connPool.conf.SpaceName == "" {
+		return nil
+	}
+	space := session.connPool.conf.SpaceName
+	resp, err := session.connection.execute(session.sessionID, fmt.Sprintf("USE `%s`", space))
+	if err != nil {
+		return fmt.Errorf("failed to use space %s, error: %w", space, err)
+	}
+	if IsError(resp) {
+		return &ExecutionError{
+			Msg: fmt.Sprintf("failed to use space %s, ErrorCode: %v, ErrorMsg: %s",
+				space, resp.GetErrorCode(), resp.GetErrorMsg()),
+			Code:      ErrorCode(resp.GetErrorCode()),
+			ServerMsg: string(resp.GetErrorMsg()),
+		}
+	}
+	session.space = space
+	return nil
+}
+
+// ReauthCount returns the number of times the session re-authenticated after it expired
+func (session *Session) ReauthCount() int {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	return session.reauthCount
+}
+
+// Authenticate again with the stored credentials and replace the expired session
+func (session *Session) reauthenticate() error {
+	username, password, err := session.credentials()
+	if err != nil {
+		return fmt.Errorf("failed to get credentials to re-authenticate expired session %d, error: %w",
+			session.sessionID, err)
+	}
+	resp, err := session.connection.authenticate(username, password)
+	if err != nil {
+		return fmt.Errorf("failed to re-authenticate expired session %d, error: %w", session.sessionID, err)
+	}
+	session.log.Info("session expired, re-authenticated", "session", session.sessionID,
+		"new_session", resp.GetSessionID())
+	session.sessionID = resp.GetSessionID()
+	session.timezoneInfo = timezoneInfo{resp.GetTimeZoneOffsetSeconds(), resp.GetTimeZoneName()}
+	session.reauthCount++
+	return session.useConfiguredSpace()
+}
+
+// Check if the server reports the session of the query expired
+func isSessionExpired(resSet *ResultSet) bool {
+	switch resSet.GetErrorCode() {
+	case ErrorCode_E_SESSION_INVALID, ErrorCode_E_SESSION_TIMEOUT,
+		ErrorCode(nebula.ErrorCode_E_SESSION_NOT_FOUND):
+		return true
+	}
+	return false
+}
+
+// Check if the query failed with a leader change or a transient storage error and can be retried
+func isRetriable(resSet *ResultSet) bool {
+	switch nebula.ErrorCode(resSet.GetErrorCode()) {
+	case nebula.ErrorCode_E_LEADER_CHANGED, nebula.ErrorCode_E_CONSENSUS_ERROR,
+		nebula.ErrorCode_E_RETRY_EXHAUSTED:
+		return true
+	}
+	return false
 }
 
 func (session *Session) reConnect() error {
-	newconnection, err := session.connPool.getIdleConn()
+	if session.connPool.conf.StickyHost {
+		// Reopen the connection to the same host, fail over to another host only if it is down
+		err := session.connection.reopen()
+		if err == nil {
+			return session.useConfiguredSpace()
+		}
+		session.log.Warn("failed to reopen connection, failing over to another host", "session", session.sessionID,
+			"host", session.connection.severAddress, "error", err)
+	}
+	exclude, err := session.connPool.hostsWithoutLabel(session.label)
+	if err != nil {
+		return err
+	}
+	newconnection, err := session.connPool.getIdleConn(exclude...)
 	if err != nil {
 		err = fmt.Errorf(err.Error())
 		return err
@@ -84,7 +685,7 @@ func (session *Session) reConnect() error {
 	// Release connection to pool
 	session.connPool.release(session.connection)
 	session.connection = newconnection
-	return nil
+	return session.useConfiguredSpace()
 }
 
 // Logout and release connetion hold by session
@@ -92,13 +693,16 @@ func (session *Session) Release() {
 	if session == nil {
 		return
 	}
+	session.mu.Lock()
+	defer session.mu.Unlock()
 	if session.connection == nil {
-		session.log.Warn("Session has been released")
+		session.log.Warn("session has been released", "session", session.sessionID)
 		return
 	}
 	if err := session.connection.signOut(session.sessionID); err != nil {
-		session.log.Warn(fmt.Sprintf("Sign out failed, %s", err.Error()))
+		session.log.Warn("sign out failed", "session", session.sessionID, "error", err)
 	}
+	session.log.Debug("released session", "session", session.sessionID, "host", session.connection.severAddress)
 	// Release connection to pool
 	session.connPool.release(session.connection)
 	session.connection = nil
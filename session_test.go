@@ -0,0 +1,291 @@
+/* Copyright (c) 2021 vesoft inc. All rights reserved.
+ *
+ * This source code is licensed under Apache 2.0 License,
+ * attached with Common Clause Condition 1.0, found in the LICENSES directory.
+ */
+
+package nebula_go
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vesoft-inc/nebula-go/v2/fake"
+	"github.com/vesoft-inc/nebula-go/v2/nebula"
+	"github.com/vesoft-inc/nebula-go/v2/nebula/graph"
+)
+
+func TestUseInvalidSpace(t *testing.T) {
+	// The names are rejected before the statement is executed
+	session := &Session{log: NoopStructuredLogger{}}
+	assert.EqualError(t, session.Use(""), "failed to use space: the space name is empty")
+	assert.EqualError(t, session.Use("a`b"),
+		"failed to use space \"a`b\": the space name contains a backtick or a line break")
+	assert.EqualError(t, session.Use("a\nb"),
+		"failed to use space \"a\\nb\": the space name contains a backtick or a line break")
+	assert.Equal(t, "", session.CurrentSpace())
+}
+
+// overlapDetector counts the requests sent while another request is in flight
+type overlapDetector struct {
+	*fake.GraphService
+	inFlight int32
+	overlaps int32
+}
+
+func (d *overlapDetector) Execute(sessionId int64, stmt []byte) (*graph.ExecutionResponse, error) {
+	if atomic.AddInt32(&d.inFlight, 1) > 1 {
+		atomic.AddInt32(&d.overlaps, 1)
+	}
+	defer atomic.AddInt32(&d.inFlight, -1)
+	time.Sleep(time.Millisecond)
+	return d.GraphService.Execute(sessionId, stmt)
+}
+
+func TestSessionConcurrentUse(t *testing.T) {
+	detector := &overlapDetector{GraphService: fake.NewGraphService()}
+	session, closeSession := newFakeSession(t, detector)
+	defer closeSession()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				_, err := session.Execute("YIELD 1")
+				assert.Nil(t, err)
+				assert.Nil(t, session.Ping())
+			}
+		}()
+	}
+	wg.Wait()
+	session.Release()
+	assert.Equal(t, int32(0), atomic.LoadInt32(&detector.overlaps))
+	assert.Len(t, detector.Executed(), 100)
+}
+
+// warnLogger records the warnings with their key-value pairs
+type warnLogger struct {
+	NoopStructuredLogger
+	warnings [][]interface{}
+}
+
+func (l *warnLogger) Warn(msg string, keysAndValues ...interface{}) {
+	l.warnings = append(l.warnings, append([]interface{}{msg}, keysAndValues...))
+}
+
+func TestSlowQueryLog(t *testing.T) {
+	service := fake.NewGraphService()
+	long := "YIELD \"" + strings.Repeat("x", 1000) + "\""
+	for _, stmt := range []string{"YIELD 1", long} {
+		service.SetResponse(stmt, &graph.ExecutionResponse{ErrorCode: nebula.ErrorCode_SUCCEEDED, LatencyInUs: 200000})
+	}
+	service.SetResponse("YIELD 2", &graph.ExecutionResponse{ErrorCode: nebula.ErrorCode_SUCCEEDED, LatencyInUs: 1000})
+	logger := &warnLogger{}
+	session, closeSession := newFakeSession(t, service, func(conf *PoolConfig) {
+		conf.SlowQueryThreshold = 100 * time.Millisecond
+		conf.Logger = logger
+	})
+	defer closeSession()
+
+	for _, stmt := range []string{"YIELD 1", "YIELD 2", long} {
+		_, err := session.Execute(stmt)
+		assert.Nil(t, err)
+	}
+	assert.Len(t, logger.warnings, 2)
+	assert.Equal(t, []interface{}{"slow query", "host", HostAddress{"127.0.0.1", 3699},
+		"latency", 200 * time.Millisecond, "statement", "YIELD 1"}, logger.warnings[0])
+	assert.True(t, strings.HasSuffix(logger.warnings[1][6].(string), "x... (496 bytes truncated)"))
+}
+
+func TestIsReadOnlyStmt(t *testing.T) {
+	readOnly := []string{
+		"MATCH (v:player) RETURN v",
+		"  go FROM 1 OVER follow YIELD follow._dst AS id | FETCH PROP ON player $-.id",
+		"LOOKUP ON player WHERE player.age > 40;",
+		"YIELD 1; SHOW SPACES",
+		"FIND SHORTEST PATH FROM 1 TO 2 OVER *",
+		"GET SUBGRAPH FROM 1",
+		"DESC TAG player",
+	}
+	for _, stmt := range readOnly {
+		assert.True(t, IsReadOnlyStmt(stmt), stmt)
+	}
+	notReadOnly := []string{
+		"",
+		" ; ",
+		"INSERT VERTEX player(name) VALUES 1:(\"Tim\")",
+		"GO FROM 1 OVER follow YIELD follow._dst AS id | DELETE VERTEX $-.id",
+		"YIELD 1; DROP SPACE test",
+		"$var = GO FROM 1 OVER follow",
+		"UPSERT VERTEX 1 SET player.age = 1",
+		"USE test",
+	}
+	for _, stmt := range notReadOnly {
+		assert.False(t, IsReadOnlyStmt(stmt), stmt)
+	}
+
+	session := &Session{connPool: &ConnectionPool{}}
+	assert.False(t, session.isReadOnly("YIELD 1"))
+	session.connPool.conf.FailoverReadOnly = true
+	assert.True(t, session.isReadOnly("YIELD 1"))
+	session.connPool.conf.ReadOnlyClassifier = func(stmt string) bool {
+		return strings.HasPrefix(stmt, "CALL")
+	}
+	assert.False(t, session.isReadOnly("YIELD 1"))
+	assert.True(t, session.isReadOnly("CALL db.labels()"))
+}
+
+type spanRecorder struct {
+	parents []interface{}
+	stmts   []string
+	traces  []QueryTrace
+}
+
+type ctxKey struct{}
+
+func (r *spanRecorder) StartQuery(ctx context.Context, stmt string) QuerySpan {
+	r.parents = append(r.parents, ctx.Value(ctxKey{}))
+	r.stmts = append(r.stmts, stmt)
+	return r
+}
+
+func (r *spanRecorder) End(info QueryTrace) {
+	r.traces = append(r.traces, info)
+}
+
+func TestTracer(t *testing.T) {
+	service := fake.NewGraphService()
+	service.SetResponse("USE test", &graph.ExecutionResponse{ErrorCode: nebula.ErrorCode_SUCCEEDED, SpaceName: []byte("test")})
+	service.SetError("RETURN", nebula.ErrorCode_E_SYNTAX_ERROR, "syntax error near `RETURN'")
+	tracer := &spanRecorder{}
+	session, closeSession := newFakeSession(t, service, func(conf *PoolConfig) {
+		conf.Tracer = tracer
+		conf.RedactStmt = func(stmt string) string {
+			return strings.Fields(stmt)[0]
+		}
+	})
+	defer closeSession()
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "parent")
+	_, err := session.ExecuteWithContext(ctx, "USE test")
+	assert.Nil(t, err)
+	_, err = session.ExecuteWithContext(ctx, "RETURN")
+	assert.Nil(t, err)
+
+	assert.Equal(t, []interface{}{"parent", "parent"}, tracer.parents)
+	assert.Equal(t, []string{"USE", "RETURN"}, tracer.stmts)
+	assert.Len(t, tracer.traces, 2)
+	assert.Equal(t, "test", tracer.traces[0].Space)
+	assert.Equal(t, "127.0.0.1:3699", tracer.traces[0].Host)
+	assert.Nil(t, tracer.traces[0].Err)
+	assert.True(t, errors.Is(tracer.traces[1].Err, ErrSyntaxError))
+}
+
+func TestSessionAddr(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	local := make(chan net.Addr, 1)
+	go func() {
+		if conn, err := listener.Accept(); err == nil {
+			local <- conn.RemoteAddr()
+			defer conn.Close()
+		}
+	}()
+	host := HostAddress{Host: "127.0.0.1", Port: listener.Addr().(*net.TCPAddr).Port}
+	conn := newConnection(host)
+	if err = conn.open(host, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	defer conn.close()
+
+	session := &Session{connection: conn}
+	remoteAddr, err := session.RemoteAddr()
+	assert.Nil(t, err)
+	assert.Equal(t, listener.Addr().String(), remoteAddr.String())
+	localAddr, err := session.LocalAddr()
+	assert.Nil(t, err)
+	select {
+	case addr := <-local:
+		assert.Equal(t, addr.String(), localAddr.String())
+	case <-time.After(time.Second):
+		t.Fatal("connection is not accepted")
+	}
+
+	// The addresses are not available without a socket
+	session.connection = &connection{severAddress: host, graph: fake.NewGraphService()}
+	_, err = session.RemoteAddr()
+	assert.NotNil(t, err)
+	session.connection = nil
+	_, err = session.LocalAddr()
+	assert.NotNil(t, err)
+}
+
+func TestExecuteJson(t *testing.T) {
+	service := fake.NewGraphService()
+	service.SetResponse("USE test", &graph.ExecutionResponse{ErrorCode: nebula.ErrorCode_SUCCEEDED, SpaceName: []byte("test")})
+	service.SetResult("MATCH (v:player) RETURN v.name AS name, v.age AS age", []string{"name", "age"},
+		[]*nebula.Value{{SVal: []byte("Tim")}, setIVal(42)})
+	service.SetError("USE nba", nebula.ErrorCode_E_SPACE_NOT_FOUND, "SpaceNotFound")
+	var ended []error
+	session, closeSession := newFakeSession(t, service, func(conf *PoolConfig) {
+		conf.MaxStmtBytes = 64
+		conf.ValidateBeforeSend = true
+		conf.Hooks.OnQueryEnd = func(stmt string, latency time.Duration, err error) {
+			ended = append(ended, err)
+		}
+	})
+	defer closeSession()
+
+	_, err := session.ExecuteJson("USE test")
+	assert.Nil(t, err)
+	assert.Equal(t, "test", session.CurrentSpace())
+	// The errors of the server are in the JSON and reported to the hooks
+	resp, err := session.ExecuteJson("USE nba")
+	assert.Nil(t, err)
+	assert.Contains(t, string(resp), "SpaceNotFound")
+	assert.Equal(t, "test", session.CurrentSpace())
+	assert.Len(t, ended, 2)
+	assert.Nil(t, ended[0])
+	assert.True(t, errors.Is(ended[1], ErrSpaceNotFound))
+
+	resp, err = session.ExecuteJson("MATCH (v:player) RETURN v.name AS name, v.age AS age")
+	assert.Nil(t, err)
+	var result struct {
+		Errors []struct {
+			Code int `json:"code"`
+		} `json:"errors"`
+		Results []struct {
+			Columns []string `json:"columns"`
+			Data    []struct {
+				Row []interface{} `json:"row"`
+			} `json:"data"`
+		} `json:"results"`
+	}
+	if err = json.Unmarshal(resp, &result); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 0, result.Errors[0].Code)
+	assert.Equal(t, []string{"name", "age"}, result.Results[0].Columns)
+	assert.Equal(t, []interface{}{"Tim", float64(42)}, result.Results[0].Data[0].Row)
+
+	// The statements are checked before they are sent
+	executed := len(service.Executed())
+	_, err = session.ExecuteJson("MATCH (v RETURN v")
+	assert.NotNil(t, err)
+	_, err = session.ExecuteJson("YIELD \"" + strings.Repeat("a", 64) + "\"")
+	assert.NotNil(t, err)
+	assert.Equal(t, executed, len(service.Executed()))
+}
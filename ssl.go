@@ -0,0 +1,96 @@
+/* Copyright (c) 2021 vesoft inc. All rights reserved.
+ *
+ * This source code is licensed under Apache 2.0 License,
+ * attached with Common Clause Condition 1.0, found in the LICENSES directory.
+ */
+
+package nebula_go
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// NewTLSConfig returns a config for NewSslConnectionPool which verifies the server certificate
+// against the CA certificates of the PEM file caCertPath and presents the client certificate
+// and key of the PEM files clientCertPath and clientKeyPath.
+// The system root CAs are used if caCertPath is empty, and no client certificate is presented
+// if clientCertPath and clientKeyPath are empty.
+// The min version is TLS 1.2 and TLS 1.2 is restricted to the suites of SecureCipherSuites.
+func NewTLSConfig(caCertPath, clientCertPath, clientKeyPath string) (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12, CipherSuites: SecureCipherSuites()}
+	if caCertPath != "" {
+		caPEM, err := ioutil.ReadFile(caCertPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA certificate, error: %w", err)
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("failed to parse CA certificate: no PEM encoded certificate found in %s", caCertPath)
+		}
+	}
+	if clientCertPath != "" || clientKeyPath != "" {
+		cert, err := tls.LoadX509KeyPair(clientCertPath, clientKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate and key, error: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// NewTLS13Config returns a config as NewTLSConfig which only accepts TLS 1.3.
+// All cipher suites of TLS 1.3 are AEAD suites vetted by the Go standard library,
+// which does not allow configuring them.
+func NewTLS13Config(caCertPath, clientCertPath, clientKeyPath string) (*tls.Config, error) {
+	config, err := NewTLSConfig(caCertPath, clientCertPath, clientKeyPath)
+	if err != nil {
+		return nil, err
+	}
+	config.MinVersion = tls.VersionTLS13
+	return config, nil
+}
+
+// SecureCipherSuites returns the TLS 1.2 cipher suites providing forward secrecy and
+// authenticated encryption
+func SecureCipherSuites() []uint16 {
+	return []uint16{
+		tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+		tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+		tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+		tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+		tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+		tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+	}
+}
+
+// Parse a hex encoded SHA-256 fingerprint, the bytes may be separated by colons
+func parseCertPin(pin string) ([sha256.Size]byte, error) {
+	var fingerprint [sha256.Size]byte
+	b, err := hex.DecodeString(strings.Replace(pin, ":", "", -1))
+	if err != nil || len(b) != sha256.Size {
+		return fingerprint, fmt.Errorf("invalid certificate fingerprint %q: must be a hex encoded SHA-256 digest", pin)
+	}
+	copy(fingerprint[:], b)
+	return fingerprint, nil
+}
+
+// Check that the fingerprint of the leaf certificate presented by the server is pinned
+func verifyPinnedCert(rawCerts [][]byte, pins [][sha256.Size]byte) error {
+	if len(rawCerts) == 0 {
+		return fmt.Errorf("certificate pinning failed: the server presented no certificate")
+	}
+	fingerprint := sha256.Sum256(rawCerts[0])
+	for _, pin := range pins {
+		if fingerprint == pin {
+			return nil
+		}
+	}
+	return fmt.Errorf("certificate pinning failed: the SHA-256 fingerprint %s of the server certificate"+
+		" matches none of the pinned fingerprints", hex.EncodeToString(fingerprint[:]))
+}
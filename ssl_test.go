@@ -0,0 +1,140 @@
+/* Copyright (c) 2021 vesoft inc. All rights reserved.
+ *
+ * This source code is licensed under Apache 2.0 License,
+ * attached with Common Clause Condition 1.0, found in the LICENSES directory.
+ */
+
+package nebula_go
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTLSConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nebula-ssl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	certPEM, keyPEM, _ := genTestCert(t)
+	certPath := filepath.Join(dir, "cert.pem")
+	keyPath := filepath.Join(dir, "key.pem")
+	badPath := filepath.Join(dir, "bad.pem")
+	assert.Nil(t, ioutil.WriteFile(certPath, certPEM, 0600))
+	assert.Nil(t, ioutil.WriteFile(keyPath, keyPEM, 0600))
+	assert.Nil(t, ioutil.WriteFile(badPath, []byte("not a pem"), 0600))
+
+	config, err := NewTLSConfig(certPath, certPath, keyPath)
+	assert.Nil(t, err)
+	assert.NotNil(t, config.RootCAs)
+	assert.Len(t, config.Certificates, 1)
+	assert.Equal(t, uint16(tls.VersionTLS12), config.MinVersion)
+	assert.Equal(t, SecureCipherSuites(), config.CipherSuites)
+
+	config, err = NewTLS13Config("", "", "")
+	assert.Nil(t, err)
+	assert.Nil(t, config.RootCAs)
+	assert.Empty(t, config.Certificates)
+	assert.Equal(t, uint16(tls.VersionTLS13), config.MinVersion)
+
+	_, err = NewTLSConfig(badPath, "", "")
+	assert.Contains(t, err.Error(), "no PEM encoded certificate")
+	_, err = NewTLSConfig(filepath.Join(dir, "missing.pem"), "", "")
+	assert.Contains(t, err.Error(), "failed to read CA certificate")
+	_, err = NewTLS13Config(certPath, certPath, badPath)
+	assert.Contains(t, err.Error(), "failed to load client certificate and key")
+}
+
+func TestCertPinning(t *testing.T) {
+	certPEM, keyPEM, der := genTestCert(t)
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.(*tls.Conn).Handshake()
+			conn.Close()
+		}
+	}()
+
+	fingerprint := sha256.Sum256(der)
+	pin, err := parseCertPin(strings.ToUpper(hex.EncodeToString(fingerprint[:])))
+	assert.Nil(t, err)
+	assert.Equal(t, fingerprint, pin)
+	_, err = parseCertPin("00:11")
+	assert.NotNil(t, err)
+
+	host := HostAddress{Host: "127.0.0.1", Port: listener.Addr().(*net.TCPAddr).Port}
+	conn := newConnection(host)
+	// The self-signed certificate is only verified by its fingerprint
+	conn.sslConfig = &tls.Config{InsecureSkipVerify: true}
+	conn.certPins = [][sha256.Size]byte{pin}
+	if err = conn.open(host, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	conn.close()
+
+	conn = newConnection(host)
+	conn.sslConfig = &tls.Config{InsecureSkipVerify: true}
+	conn.certPins = [][sha256.Size]byte{{}}
+	err = conn.open(host, time.Second)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "certificate pinning failed")
+}
+
+// Generate a self-signed certificate for 127.0.0.1, returns the PEM encoded certificate and key
+// and the DER encoded certificate
+func genTestCert(t *testing.T) ([]byte, []byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "nebula"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), der
+}
@@ -0,0 +1,67 @@
+/* Copyright (c) 2021 vesoft inc. All rights reserved.
+ *
+ * This source code is licensed under Apache 2.0 License,
+ * attached with Common Clause Condition 1.0, found in the LICENSES directory.
+ */
+
+package nebula_go
+
+import (
+	"context"
+	"time"
+)
+
+// Tracer starts a span for each query executed by the sessions of the pool, as a child of the span
+// carried by the context given to Session.ExecuteWithContext. It keeps the client free of a tracing
+// dependency, e.g. an adapter of an OpenTelemetry tracer:
+//
+//	type otelTracer struct{ tracer trace.Tracer }
+//
+//	func (t otelTracer) StartQuery(ctx context.Context, stmt string) nebula_go.QuerySpan {
+//		_, span := t.tracer.Start(ctx, "nebula.query", trace.WithSpanKind(trace.SpanKindClient),
+//			trace.WithAttributes(attribute.String("db.statement", stmt)))
+//		return otelSpan{span}
+//	}
+//
+//	type otelSpan struct{ span trace.Span }
+//
+//	func (s otelSpan) End(info nebula_go.QueryTrace) {
+//		s.span.SetAttributes(attribute.String("db.name", info.Space), attribute.String("net.peer.name", info.Host))
+//		if info.Err != nil {
+//			s.span.RecordError(info.Err)
+//			s.span.SetStatus(codes.Error, info.Err.Error())
+//		}
+//		s.span.End()
+//	}
+type Tracer interface {
+	// StartQuery starts the span of the query before it is sent, stmt is redacted as set by
+	// RedactLiterals and RedactStmt of the pool config
+	StartQuery(ctx context.Context, stmt string) QuerySpan
+}
+
+// QuerySpan is the span of a query started by a Tracer
+type QuerySpan interface {
+	// End ends the span when the query completed
+	End(info QueryTrace)
+}
+
+// QueryTrace is the outcome of a query reported to its QuerySpan
+type QueryTrace struct {
+	// The space of the session after the query, empty if none is used
+	Space string
+	// The host:port of the graph service which executed the query, empty if the session was released
+	Host string
+	// The time spent executing the query, including the retries
+	Latency time.Duration
+	// The error returned by the execution, or an error holding the error code and message of the server
+	// if the query is not succeeded
+	Err error
+}
+
+// Start the span of the query if a tracer is set in the pool config
+func (conf *PoolConfig) startQuerySpan(ctx context.Context, stmt string) QuerySpan {
+	if conf.Tracer == nil {
+		return nil
+	}
+	return conf.Tracer.StartQuery(ctx, conf.redactStmt(stmt))
+}
@@ -8,9 +8,11 @@ package nebula_go
 
 import (
 	"fmt"
+	"math"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/vesoft-inc/nebula-go/v2/nebula"
 )
@@ -84,35 +86,90 @@ func (valWrap ValueWrapper) AsNull() (nebula.NullType, error) {
 	if valWrap.value.IsSetNVal() {
 		return valWrap.value.GetNVal(), nil
 	}
-	return -1, fmt.Errorf("failed to convert value %s to Null", valWrap.GetType())
+	return -1, valWrap.conversionError("Null")
 }
 
+// AsBool returns a BOOL value, a *ConversionError is returned for the other types
 func (valWrap ValueWrapper) AsBool() (bool, error) {
 	if valWrap.value.IsSetBVal() {
 		return valWrap.value.GetBVal(), nil
 	}
-	return false, fmt.Errorf("failed to convert value %s to bool", valWrap.GetType())
+	return false, valWrap.conversionError("bool")
 }
 
+// AsInt returns an INT value, a *ConversionError is returned for the other types, see ToInt
 func (valWrap ValueWrapper) AsInt() (int64, error) {
 	if valWrap.value.IsSetIVal() {
 		return valWrap.value.GetIVal(), nil
 	}
-	return -1, fmt.Errorf("failed to convert value %s to int", valWrap.GetType())
+	return -1, valWrap.conversionError("int")
 }
 
+// AsFloat returns a FLOAT value, a *ConversionError is returned for the other types, see ToFloat
 func (valWrap ValueWrapper) AsFloat() (float64, error) {
 	if valWrap.value.IsSetFVal() {
 		return valWrap.value.GetFVal(), nil
 	}
-	return -1, fmt.Errorf("failed to convert value %s to float", valWrap.GetType())
+	return -1, valWrap.conversionError("float")
 }
 
+// AsString returns a STRING value, a *ConversionError is returned for the other types
 func (valWrap ValueWrapper) AsString() (string, error) {
 	if valWrap.value.IsSetSVal() {
 		return string(valWrap.value.GetSVal()), nil
 	}
-	return "", fmt.Errorf("failed to convert value %s to string", valWrap.GetType())
+	return "", valWrap.conversionError("string")
+}
+
+// ToInt returns an INT value, or a FLOAT value converted to an integer. A FLOAT with a fractional
+// part is truncated toward zero if truncate is true and rejected otherwise.
+// A *ConversionError is returned for the other types, NaN, the infinities and the FLOAT values
+// out of the range of int64.
+func (valWrap ValueWrapper) ToInt(truncate bool) (int64, error) {
+	if valWrap.value.IsSetIVal() {
+		return valWrap.value.GetIVal(), nil
+	}
+	if !valWrap.value.IsSetFVal() {
+		return -1, valWrap.conversionError("int")
+	}
+	f := valWrap.value.GetFVal()
+	if math.IsNaN(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+		return -1, &ConversionError{
+			Msg:    fmt.Sprintf("failed to convert value float %v to int, value is out of range", f),
+			Type:   valWrap.GetType(),
+			Target: "int",
+		}
+	}
+	if f != math.Trunc(f) && !truncate {
+		return -1, &ConversionError{
+			Msg:    fmt.Sprintf("failed to convert value float %v to int, value has a fractional part", f),
+			Type:   valWrap.GetType(),
+			Target: "int",
+		}
+	}
+	return int64(f), nil
+}
+
+// ToFloat returns a FLOAT value, or an INT value converted to a float.
+// A *ConversionError is returned for the other types and the INT values beyond ±2^53,
+// which cannot be represented exactly by a float.
+func (valWrap ValueWrapper) ToFloat() (float64, error) {
+	if valWrap.value.IsSetFVal() {
+		return valWrap.value.GetFVal(), nil
+	}
+	if !valWrap.value.IsSetIVal() {
+		return -1, valWrap.conversionError("float")
+	}
+	i := valWrap.value.GetIVal()
+	const maxExactInt = 1 << 53
+	if i > maxExactInt || i < -maxExactInt {
+		return -1, &ConversionError{
+			Msg:    fmt.Sprintf("failed to convert value int %d to float, value cannot be represented exactly", i),
+			Type:   valWrap.GetType(),
+			Target: "float",
+		}
+	}
+	return float64(i), nil
 }
 
 func (valWrap ValueWrapper) AsTime() (*TimeWrapper, error) {
@@ -124,14 +181,14 @@ func (valWrap ValueWrapper) AsTime() (*TimeWrapper, error) {
 		}
 		return time, nil
 	}
-	return nil, fmt.Errorf("failed to convert value %s to Time", valWrap.GetType())
+	return nil, valWrap.conversionError("Time")
 }
 
 func (valWrap ValueWrapper) AsDate() (*nebula.Date, error) {
 	if valWrap.value.IsSetDVal() {
 		return valWrap.value.GetDVal(), nil
 	}
-	return nil, fmt.Errorf("failed to convert value %s to Date", valWrap.GetType())
+	return nil, valWrap.conversionError("Date")
 }
 
 func (valWrap ValueWrapper) AsDateTime() (*DateTimeWrapper, error) {
@@ -143,7 +200,29 @@ func (valWrap ValueWrapper) AsDateTime() (*DateTimeWrapper, error) {
 		}
 		return timeDate, nil
 	}
-	return nil, fmt.Errorf("failed to convert value %s to DateTime", valWrap.GetType())
+	return nil, valWrap.conversionError("DateTime")
+}
+
+// AsGoTime converts a DATE, TIME or DATETIME value to time.Time.
+// TIME and DATETIME are converted from UTC to the timezone offset from graph service,
+// the date of a TIME value is 0001-01-01 in UTC and the time of a DATE value is midnight.
+func (valWrap ValueWrapper) AsGoTime() (time.Time, error) {
+	location := time.FixedZone(string(valWrap.timezoneInfo.name), int(valWrap.timezoneInfo.offset))
+	if valWrap.value.IsSetDVal() {
+		date := valWrap.value.GetDVal()
+		return time.Date(int(date.Year), time.Month(date.Month), int(date.Day), 0, 0, 0, 0, location), nil
+	} else if valWrap.value.IsSetTVal() {
+		t := valWrap.value.GetTVal()
+		return time.Date(1, time.January, 1,
+			int(t.Hour), int(t.Minute), int(t.Sec), int(t.Microsec)*1000,
+			time.UTC).In(location), nil
+	} else if valWrap.value.IsSetDtVal() {
+		dt := valWrap.value.GetDtVal()
+		return time.Date(int(dt.Year), time.Month(dt.Month), int(dt.Day),
+			int(dt.Hour), int(dt.Minute), int(dt.Sec), int(dt.Microsec)*1000,
+			time.UTC).In(location), nil
+	}
+	return time.Time{}, valWrap.conversionError("time.Time")
 }
 
 func (valWrap ValueWrapper) AsList() ([]ValueWrapper, error) {
@@ -155,7 +234,7 @@ func (valWrap ValueWrapper) AsList() ([]ValueWrapper, error) {
 		}
 		return varList, nil
 	}
-	return nil, fmt.Errorf("failed to convert value %s to List", valWrap.GetType())
+	return nil, valWrap.conversionError("List")
 }
 
 func (valWrap ValueWrapper) AsDedupList() ([]ValueWrapper, error) {
@@ -167,7 +246,7 @@ func (valWrap ValueWrapper) AsDedupList() ([]ValueWrapper, error) {
 		}
 		return varList, nil
 	}
-	return nil, fmt.Errorf("failed to convert value %s to set(deduped list)", valWrap.GetType())
+	return nil, valWrap.conversionError("set(deduped list)")
 }
 
 func (valWrap ValueWrapper) AsMap() (map[string]ValueWrapper, error) {
@@ -180,12 +259,16 @@ func (valWrap ValueWrapper) AsMap() (map[string]ValueWrapper, error) {
 		}
 		return newMap, nil
 	}
-	return nil, fmt.Errorf("failed to convert value %s to Map", valWrap.GetType())
+	return nil, valWrap.conversionError("Map")
 }
 
 func (valWrap ValueWrapper) AsNode() (*Node, error) {
 	if !valWrap.value.IsSetVVal() {
-		return nil, fmt.Errorf("failed to convert value %s to Node, value is not an vertex", valWrap.GetType())
+		return nil, &ConversionError{
+			Msg:    fmt.Sprintf("failed to convert value %s to Node, value is not an vertex", valWrap.GetType()),
+			Type:   valWrap.GetType(),
+			Target: "Node",
+		}
 	}
 	vertex := valWrap.value.VVal
 	node, err := genNode(vertex, valWrap.timezoneInfo)
@@ -197,7 +280,11 @@ func (valWrap ValueWrapper) AsNode() (*Node, error) {
 
 func (valWrap ValueWrapper) AsRelationship() (*Relationship, error) {
 	if !valWrap.value.IsSetEVal() {
-		return nil, fmt.Errorf("failed to convert value %s to Relationship, value is not an edge", valWrap.GetType())
+		return nil, &ConversionError{
+			Msg:    fmt.Sprintf("failed to convert value %s to Relationship, value is not an edge", valWrap.GetType()),
+			Type:   valWrap.GetType(),
+			Target: "Relationship",
+		}
 	}
 	edge := valWrap.value.EVal
 	relationship, err := genRelationship(edge, valWrap.timezoneInfo)
@@ -209,7 +296,11 @@ func (valWrap ValueWrapper) AsRelationship() (*Relationship, error) {
 
 func (valWrap ValueWrapper) AsPath() (*PathWrapper, error) {
 	if !valWrap.value.IsSetPVal() {
-		return nil, fmt.Errorf("failed to convert value %s to PathWrapper, value is not an edge", valWrap.GetType())
+		return nil, &ConversionError{
+			Msg:    fmt.Sprintf("failed to convert value %s to PathWrapper, value is not a path", valWrap.GetType()),
+			Type:   valWrap.GetType(),
+			Target: "PathWrapper",
+		}
 	}
 	path, err := genPathWrapper(valWrap.value.PVal, valWrap.timezoneInfo)
 	if err != nil {
@@ -218,6 +309,90 @@ func (valWrap ValueWrapper) AsPath() (*PathWrapper, error) {
 	return path, nil
 }
 
+// AsNativeGo recursively converts the value into Go types:
+//
+//	null, empty: nil
+//	bool, int, float, string: bool, int64, float64, string
+//	date, time, datetime: time.Time as returned by AsGoTime
+//	list, set: []interface{}
+//	map: map[string]interface{}
+//	vertex, edge, path: *Node, *Relationship, *PathWrapper
+//	dataset: [][]interface{} holding the rows
+func (valWrap ValueWrapper) AsNativeGo() (interface{}, error) {
+	value := valWrap.value
+	if value == nil {
+		return nil, nil
+	}
+	// GetType reports a dataset as empty
+	if value.IsSetGVal() {
+		rows := make([][]interface{}, 0, len(value.GetGVal().Rows))
+		for _, row := range value.GetGVal().Rows {
+			native, err := valWrap.nativeList(row.Values)
+			if err != nil {
+				return nil, err
+			}
+			rows = append(rows, native)
+		}
+		return rows, nil
+	}
+	switch valWrap.GetType() {
+	case "null", "empty":
+		return nil, nil
+	case "bool":
+		return value.GetBVal(), nil
+	case "int":
+		return value.GetIVal(), nil
+	case "float":
+		return value.GetFVal(), nil
+	case "string":
+		return string(value.GetSVal()), nil
+	case "date", "time", "datetime":
+		return valWrap.AsGoTime()
+	case "list":
+		return valWrap.nativeList(value.GetLVal().Values)
+	case "set":
+		return valWrap.nativeList(value.GetUVal().Values)
+	case "map":
+		kvs := make(map[string]interface{}, len(value.GetMVal().Kvs))
+		for k, v := range value.GetMVal().Kvs {
+			native, err := ValueWrapper{v, valWrap.timezoneInfo}.AsNativeGo()
+			if err != nil {
+				return nil, err
+			}
+			kvs[k] = native
+		}
+		return kvs, nil
+	case "vertex":
+		return valWrap.AsNode()
+	case "edge":
+		return valWrap.AsRelationship()
+	case "path":
+		return valWrap.AsPath()
+	}
+	return nil, valWrap.conversionError("Go type")
+}
+
+func (valWrap ValueWrapper) nativeList(values []*nebula.Value) ([]interface{}, error) {
+	list := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		native, err := ValueWrapper{v, valWrap.timezoneInfo}.AsNativeGo()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, native)
+	}
+	return list, nil
+}
+
+// Return the error of a value whose type cannot be converted to target
+func (valWrap ValueWrapper) conversionError(target string) *ConversionError {
+	return &ConversionError{
+		Msg:    fmt.Sprintf("failed to convert value %s to %s", valWrap.GetType(), target),
+		Type:   valWrap.GetType(),
+		Target: target,
+	}
+}
+
 // Returns the value type of value in the valWrap in string
 func (valWrap ValueWrapper) GetType() string {
 	if valWrap.value.IsSetNVal() {